@@ -3,6 +3,7 @@ module github.com/m-sossich/gonphig
 go 1.25.0
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/stretchr/testify v1.11.1
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,41 +1,213 @@
 package parser
 
 import (
-	"bufio"
-	"bytes"
+	"fmt"
+	"os"
 	"strings"
 )
 
 // DotEnv parses a .env file into target, which must be a *map[string]string.
+// The map target points to on entry holds the keys of earlier .env files; it
+// is replaced with the keys of this one.
 //
 // Supported syntax:
 //
-//	KEY=value         standard form
-//	export KEY=value  export prefix is stripped
-//	# comment         ignored
-//	(empty lines)     ignored
+//	KEY=value              standard form
+//	export KEY=value       export prefix is stripped
+//	KEY=value # note       trailing comment after whitespace is stripped
+//	KEY="a b\nc"           double quotes: \n, \r, \t, \", \\ and \$ escapes
+//	KEY='a b\nc'           single quotes: literal, no escapes
+//	KEY="-----BEGIN ...    quoted values may span several lines, up to the
+//	...-----"              closing quote
+//	URL=http://${HOST}     variable expansion, see Expand
+//	PASS=pa$$word          $$ is a literal $, as is \$ outside single quotes
+//	# comment              ignored
+//	(empty lines)          ignored
 //
-// Quotes and variable expansion are not supported.
+// Variables are expanded in unquoted and double-quoted values, resolving
+// against real environment variables first, then keys assigned earlier in the
+// same file, then keys of earlier .env files. Single-quoted values, $$ and
+// escaped \$ are never expanded; unquoted values resolve no other backslash
+// escape.
+//
+// Lines without "=" are skipped and a repeated key keeps its last value. An
+// unterminated quoted value is an error. DotEnvStrict rejects the lenient
+// cases instead.
 var DotEnv FileParser = func(data []byte, target any) error {
+	return parseDotEnvInto(data, target, false)
+}
+
+// DotEnvStrict is the strict variant of DotEnv. Besides the syntax errors
+// DotEnv reports, it returns a *SyntaxError for:
+//
+//   - a line without "="
+//   - an invalid key name — keys must start with a letter or underscore and
+//     contain only letters, digits, "_", "." and "-"
+//   - a key assigned more than once
+//   - text other than a comment after a closing quote
+var DotEnvStrict FileParser = func(data []byte, target any) error {
+	return parseDotEnvInto(data, target, true)
+}
+
+// SyntaxError reports a malformed line in a dotenv file. Line is 1-based and
+// Text is the offending line as written.
+type SyntaxError struct {
+	Line int
+	Text string
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Msg, e.Text)
+}
+
+func parseDotEnvInto(data []byte, target any, strict bool) error {
 	m := target.(*map[string]string)
+	earlier := *m
 	*m = make(map[string]string)
 
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+	entries, err := parseDotEnv(data, strict)
+	if err != nil {
+		return err
+	}
+	lookup := func(name string) (string, bool) {
+		if val := os.Getenv(name); val != "" {
+			return val, true
+		}
+		if val, ok := (*m)[name]; ok {
+			return val, true
+		}
+		val, ok := earlier[name]
+		return val, ok
+	}
+	for _, e := range entries {
+		value := e.value
+		if e.quote != '\'' {
+			mode := dollarEscape
+			if e.quote == '"' {
+				mode = allEscapes
+			}
+			if value, err = interpolate(value, mode, lookup); err != nil {
+				return fmt.Errorf("line %d: %w", e.line, err)
+			}
+		}
+		(*m)[e.key] = value
+	}
+	return nil
+}
+
+// dotenvEntry is a single KEY=value assignment. value is raw: quotes are
+// removed but escapes and variable references are left for DotEnv to
+// resolve. quote is the quote character the value was wrapped in, or 0.
+// line is the 1-based line the assignment starts on.
+type dotenvEntry struct {
+	key   string
+	value string
+	quote byte
+	line  int
+}
+
+// parseDotEnv splits data into assignments in file order. In strict mode
+// lines DotEnv would skip or silently accept are reported as *SyntaxError.
+func parseDotEnv(data []byte, strict bool) ([]dotenvEntry, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	var entries []dotenvEntry
+	seen := make(map[string]int)
+	for i := 0; i < len(lines); i++ {
+		start, text := i+1, lines[i]
+		line := strings.TrimLeft(text, " \t")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
-		idx := strings.IndexByte(line, '=')
-		if idx < 0 {
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimLeft(strings.TrimPrefix(line, "export "), " \t")
+		}
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			if strict {
+				return nil, &SyntaxError{Line: start, Text: text, Msg: `missing "="`}
+			}
 			continue
 		}
-		key := strings.TrimSpace(line[:idx])
-		value := strings.TrimSpace(line[idx+1:])
+		key = strings.TrimSpace(key)
+		trimmed := strings.TrimLeft(rest, " \t")
+
+		var (
+			value string
+			quote byte
+		)
+		if trimmed != "" && (trimmed[0] == '"' || trimmed[0] == '\'') {
+			quote = trimmed[0]
+			body := trimmed[1:]
+			end := closingQuote(body, quote)
+			for end < 0 {
+				if i++; i >= len(lines) {
+					return nil, &SyntaxError{Line: start, Text: text, Msg: "unterminated quoted value"}
+				}
+				body += "\n" + lines[i]
+				end = closingQuote(body, quote)
+			}
+			value = body[:end]
+			if strict && stripComment(" "+body[end+1:]) != "" {
+				return nil, &SyntaxError{Line: i + 1, Text: lines[i], Msg: "unexpected text after closing quote"}
+			}
+		} else {
+			value = stripComment(rest)
+		}
+
+		if strict {
+			if !validKey(key) {
+				return nil, &SyntaxError{Line: start, Text: text, Msg: "invalid key name"}
+			}
+			if first, dup := seen[key]; dup {
+				return nil, &SyntaxError{Line: start, Text: text, Msg: fmt.Sprintf("duplicate key %s, first set on line %d", key, first)}
+			}
+			seen[key] = start
+		}
 		if key != "" {
-			(*m)[key] = value
+			entries = append(entries, dotenvEntry{key: key, value: value, quote: quote, line: start})
+		}
+	}
+	return entries, nil
+}
+
+// validKey reports whether key is acceptable in strict mode.
+func validKey(key string) bool {
+	if key == "" || !isNameStart(key[0]) {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		if c := key[i]; !isNameChar(c) && c != '.' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// closingQuote returns the index in s of the quote ending a value opened with
+// quote, or -1 if s does not contain it. Inside double quotes a backslash
+// escapes the following character.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment trims an unquoted value and drops a trailing comment. A "#"
+// only starts a comment when preceded by whitespace, so KEY=a#b keeps "a#b"
+// while KEY= # note is empty.
+func stripComment(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			s = s[:i]
+			break
 		}
 	}
-	return scanner.Err()
+	return strings.TrimSpace(s)
 }
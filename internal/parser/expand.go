@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expand replaces variable references in s with values from lookup:
+//
+//	$VAR, ${VAR}      value of VAR, or "" when unset
+//	${VAR:-default}   default when VAR is unset or empty; default may itself
+//	                  contain references
+//	${VAR:?message}   error when VAR is unset or empty
+//
+// "$$" is a literal "$", as in docker-compose files, and a "$" not followed by
+// a name or "{" is kept literally.
+func Expand(s string, lookup func(string) (string, bool)) (string, error) {
+	return interpolate(s, noEscapes, lookup)
+}
+
+// escapeMode selects the backslash escapes interpolate resolves.
+type escapeMode int
+
+const (
+	// noEscapes keeps every backslash literally.
+	noEscapes escapeMode = iota
+	// dollarEscape resolves only \$, as in unquoted dotenv values.
+	dollarEscape
+	// allEscapes resolves the escapes of double-quoted dotenv values.
+	allEscapes
+)
+
+// interpolate expands the references in s, resolving the escape sequences
+// mode allows, so that an escaped \$ — like $$ — is kept as a literal dollar
+// sign instead of being expanded.
+func interpolate(s string, mode escapeMode, lookup func(string) (string, bool)) (string, error) {
+	if !strings.ContainsAny(s, `$\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && mode == allEscapes && i+1 < len(s):
+			i++
+			writeEscape(&b, s[i])
+		case c == '\\' && mode == dollarEscape && i+1 < len(s) && s[i+1] == '$':
+			i++
+			b.WriteByte('$')
+		case c == '$' && i+1 < len(s) && s[i+1] == '$':
+			i++
+			b.WriteByte('$')
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference %q", s[i:])
+			}
+			val, err := expandBraced(s[i+2:end], mode, lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+			i = end
+		case c == '$' && i+1 < len(s) && isNameStart(s[i+1]):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			val, _ := lookup(s[i+1 : j])
+			b.WriteString(val)
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced resolves the inside of a ${...} reference, expanding a default
+// with the escapes of the value holding it.
+func expandBraced(ref string, mode escapeMode, lookup func(string) (string, bool)) (string, error) {
+	name, op, arg := ref, "", ""
+	if idx := strings.IndexByte(ref, ':'); idx >= 0 && idx+1 < len(ref) {
+		name, op, arg = ref[:idx], ref[idx:idx+2], ref[idx+2:]
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in ${%s}", ref)
+	}
+	val, _ := lookup(name)
+	switch op {
+	case "":
+		return val, nil
+	case ":-":
+		if val != "" {
+			return val, nil
+		}
+		return interpolate(arg, mode, lookup)
+	case ":?":
+		if val != "" {
+			return val, nil
+		}
+		if arg == "" {
+			arg = "required variable is not set"
+		}
+		return "", fmt.Errorf("%s: %s", name, arg)
+	default:
+		return "", fmt.Errorf("unsupported variable reference ${%s}", ref)
+	}
+}
+
+// closingBrace returns the index of the "}" closing a reference whose body
+// starts at from, accounting for nested ${...} in defaults, or -1.
+func closingBrace(s string, from int) int {
+	depth := 0
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '$':
+			i++
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// writeEscape writes the character an escape sequence \c stands for.
+// Unknown sequences are kept verbatim.
+func writeEscape(b *strings.Builder, c byte) {
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case '"', '\\', '$':
+		b.WriteByte(c)
+	default:
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
+}
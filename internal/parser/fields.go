@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldsFunc reports which fields of the struct type t are explicitly set by
+// data. It maps dotted Go field paths (e.g. "DB.Port") to the dotted key that
+// sets them in data (e.g. "db.port"). Only leaves are reported: a nested
+// struct written as a mapping contributes its fields, not itself. Presence is
+// what lets an explicit zero value (port: 0, enabled: false) be told apart
+// from a field the file never mentions.
+type FieldsFunc func(data []byte, t reflect.Type) (map[string]string, error)
+
+// fieldsRegistry maps built-in KindStruct formats to their FieldsFunc.
+// Parsers registered through Register have none; Load falls back to
+// comparing the struct before and after decoding.
+var fieldsRegistry = map[string]FieldsFunc{
+	".yml":  YAMLFields,
+	".yaml": YAMLFields,
+	".toml": TOMLFields,
+	".json": JSONFields,
+}
+
+// Fields returns the FieldsFunc for the extension of path, or nil when the
+// format has none.
+func Fields(path string) FieldsFunc {
+	mu.RLock()
+	defer mu.RUnlock()
+	return fieldsRegistry[filepath.Ext(path)]
+}
+
+// YAMLFields decodes data into a yaml.Node tree and matches mapping keys to
+// fields the way the YAML decoder does: by yaml tag name, or by the
+// lowercased field name. Inline fields and merge keys are followed.
+var YAMLFields FieldsFunc = func(data []byte, t reflect.Type) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	if len(doc.Content) > 0 {
+		yamlNodeFields(doc.Content[0], t, "", "", out)
+	}
+	return out, nil
+}
+
+// TOMLFields reports the fields set by TOML data, resolving keys with the
+// same toml → yaml → field name fallback as the TOML parser.
+var TOMLFields FieldsFunc = func(data []byte, t reflect.Type) (map[string]string, error) {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	tomlMapFields(raw, t, "", "", out)
+	return out, nil
+}
+
+// JSONFields matches object keys to fields the way encoding/json does: by
+// json tag name, or by field name ignoring case. Untagged embedded structs
+// are treated as inline.
+var JSONFields FieldsFunc = func(data []byte, t reflect.Type) (map[string]string, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	jsonMapFields(raw, t, "", "", out)
+	return out, nil
+}
+
+// yamlNodeFields records the fields set by mapping n. prefix is the Go field
+// path of n and keyPrefix its key path in the document.
+func yamlNodeFields(n *yaml.Node, t reflect.Type, prefix, keyPrefix string, out map[string]string) {
+	n = resolveAlias(n)
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], resolveAlias(n.Content[i+1])
+		if key.Value == "<<" && key.Tag == "!!merge" {
+			for _, m := range mergeSources(val) {
+				yamlNodeFields(m, t, prefix, keyPrefix, out)
+			}
+			continue
+		}
+		f, inline, ok := yamlField(t, key.Value)
+		if !ok {
+			continue
+		}
+		path := prefix + joinNames(inline) + f.Name
+		markField(path, keyPrefix+key.Value, f.Type, val.Kind == yaml.MappingNode, out, func(ft reflect.Type, p, k string) {
+			yamlNodeFields(val, ft, p, k, out)
+		})
+	}
+}
+
+func tomlMapFields(m map[string]any, t reflect.Type, prefix, keyPrefix string, out map[string]string) {
+	for key, val := range m {
+		f, ok := tomlField(t, key)
+		if !ok {
+			continue
+		}
+		nested, isMap := val.(map[string]any)
+		markField(prefix+f.Name, keyPrefix+key, f.Type, isMap, out, func(ft reflect.Type, p, k string) {
+			tomlMapFields(nested, ft, p, k, out)
+		})
+	}
+}
+
+func jsonMapFields(m map[string]any, t reflect.Type, prefix, keyPrefix string, out map[string]string) {
+	for key, val := range m {
+		f, inline, ok := jsonField(t, key)
+		if !ok {
+			continue
+		}
+		path := prefix + joinNames(inline) + f.Name
+		nested, isMap := val.(map[string]any)
+		markField(path, keyPrefix+key, f.Type, isMap, out, func(ft reflect.Type, p, k string) {
+			jsonMapFields(nested, ft, p, k, out)
+		})
+	}
+}
+
+// markField records path as set by key, or, when the value is a mapping and
+// the field is a struct (or pointer to one), descends into it with recurse.
+func markField(path, key string, ft reflect.Type, isMapping bool, out map[string]string, recurse func(reflect.Type, string, string)) {
+	for ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if isMapping && ft.Kind() == reflect.Struct {
+		recurse(ft, path+".", key+".")
+		return
+	}
+	out[path] = key
+}
+
+// yamlField finds the field of t that key names, searching inline structs.
+// inline lists the inline fields traversed to reach it, outermost first.
+func yamlField(t reflect.Type, key string) (reflect.StructField, []reflect.StructField, bool) {
+	return findField(t, key, func(f reflect.StructField) (string, bool, bool) {
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			return "", true, true
+		}
+		if name == "-" {
+			return "", false, false
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		return name, false, true
+	}, func(a, b string) bool { return a == b })
+}
+
+func jsonField(t reflect.Type, key string) (reflect.StructField, []reflect.StructField, bool) {
+	return findField(t, key, func(f reflect.StructField) (string, bool, bool) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return "", false, false
+		}
+		if name == "" && f.Anonymous && f.Type.Kind() == reflect.Struct {
+			return "", true, true
+		}
+		if name == "" {
+			name = f.Name
+		}
+		return name, false, true
+	}, strings.EqualFold)
+}
+
+// findField looks key up among the exported fields of struct type t. name
+// reports a field's key, whether it is inline, and whether it is decoded at
+// all; match compares keys. Inline structs are searched recursively.
+func findField(t reflect.Type, key string, name func(reflect.StructField) (string, bool, bool), match func(a, b string) bool) (reflect.StructField, []reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, nil, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		n, inline, ok := name(f)
+		switch {
+		case !ok:
+		case inline:
+			if found, path, ok := findField(f.Type, key, name, match); ok {
+				return found, append([]reflect.StructField{f}, path...), true
+			}
+		case match(n, key):
+			return f, nil, true
+		}
+	}
+	return reflect.StructField{}, nil, false
+}
+
+func joinNames(fields []reflect.StructField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f.Name)
+		b.WriteByte('.')
+	}
+	return b.String()
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// mergeSources returns the mappings a YAML merge key (<<) pulls in: a single
+// mapping or a sequence of them.
+func mergeSources(n *yaml.Node) []*yaml.Node {
+	if n.Kind == yaml.SequenceNode {
+		out := make([]*yaml.Node, 0, len(n.Content))
+		for _, c := range n.Content {
+			out = append(out, resolveAlias(c))
+		}
+		return out
+	}
+	return []*yaml.Node{n}
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// JSON unmarshals JSON-encoded data into target via json.Unmarshal. Keys
+// without a matching field are ignored.
+//
+// A time.Duration may be written as a string such as "30s", as in YAML and
+// TOML, or as a number of nanoseconds, which is all encoding/json accepts.
+var JSON FileParser = func(data []byte, target any) error {
+	return decodeJSON(data, target, false)
+}
+
+// JSONStrict is the strict variant of JSON: a key without a matching field is
+// an error rather than being dropped silently, so a misspelled key in a
+// deployed config fails loudly.
+var JSONStrict FileParser = func(data []byte, target any) error {
+	return decodeJSON(data, target, true)
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// decodeJSON decodes data into target, first rewriting the duration strings
+// it holds for time.Duration fields as nanoseconds. Data without any is
+// decoded as is, so error offsets point into the file.
+func decodeJSON(data []byte, target any, strict bool) error {
+	var raw any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	changed, err := jsonDurations(raw, reflect.TypeOf(target).Elem(), "")
+	if err != nil {
+		return err
+	}
+	if changed {
+		if data, err = json.Marshal(raw); err != nil {
+			return err
+		}
+	}
+	dec = json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(target)
+}
+
+// jsonDurations walks the decoded value v alongside the type t it is decoded
+// into and replaces, in place, every string held for a time.Duration with its
+// number of nanoseconds. It reports whether it replaced any. key is the
+// dotted key path of v, for errors.
+func jsonDurations(v any, t reflect.Type, key string) (bool, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	changed := false
+	set := func(val any, t reflect.Type, key string, replace func(any)) error {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if s, ok := val.(string); ok && t == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("json: %s: %w", key, err)
+			}
+			replace(json.Number(strconv.FormatInt(int64(d), 10)))
+			changed = true
+			return nil
+		}
+		c, err := jsonDurations(val, t, key)
+		changed = changed || c
+		return err
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			var ft reflect.Type
+			switch t.Kind() {
+			case reflect.Struct:
+				f, _, ok := jsonField(t, k)
+				if !ok {
+					continue
+				}
+				ft = f.Type
+			case reflect.Map:
+				ft = t.Elem()
+			default:
+				continue
+			}
+			if err := set(val, ft, joinKey(key, k), func(n any) { v[k] = n }); err != nil {
+				return false, err
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			break
+		}
+		for i, val := range v {
+			if err := set(val, t.Elem(), fmt.Sprintf("%s[%d]", key, i), func(n any) { v[i] = n }); err != nil {
+				return false, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// joinKey appends key to the dotted key path prefix.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
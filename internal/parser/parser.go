@@ -1,8 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 // FileParser parses the raw bytes of a configuration file and writes the
@@ -15,7 +19,7 @@ type Kind uint8
 
 const (
 	// KindStruct parsers unmarshal directly into the configuration struct
-	// (e.g. YAML). Load passes the struct pointer as target.
+	// (e.g. YAML, TOML, JSON). Load passes the struct pointer as target.
 	KindStruct Kind = iota
 	// KindKV parsers produce a flat key-value map (e.g. dotenv). Load passes
 	// a *map[string]string as target; the loader uses the map as a fallback
@@ -23,23 +27,99 @@ const (
 	KindKV
 )
 
+// entry is a registered parser. strict is the optional strict variant used
+// when the caller asks for strict parsing; parsers without one are used as is.
 type entry struct {
 	parser FileParser
+	strict FileParser
 	kind   Kind
 }
 
-var registry = map[string]entry{
-	".yml":  {parser: YAML, kind: KindStruct},
-	".yaml": {parser: YAML, kind: KindStruct},
-	".env":  {parser: DotEnv, kind: KindKV},
+var (
+	mu       sync.RWMutex
+	registry = map[string]entry{
+		".yml":  {parser: YAML, kind: KindStruct},
+		".yaml": {parser: YAML, kind: KindStruct},
+		".toml": {parser: TOML, kind: KindStruct},
+		".json": {parser: JSON, strict: JSONStrict, kind: KindStruct},
+		".env":  {parser: DotEnv, strict: DotEnvStrict, kind: KindKV},
+	}
+)
+
+// Register adds p as the parser for files with extension ext, replacing any
+// parser already registered for it — including the built-in ones. The leading
+// dot of ext is optional. Register is safe for concurrent use with Lookup.
+func Register(ext string, p FileParser, kind Kind) error {
+	if ext = strings.TrimPrefix(ext, "."); ext == "" {
+		return errors.New("parser extension must not be empty")
+	}
+	if p == nil {
+		return fmt.Errorf("parser for %q must not be nil", "."+ext)
+	}
+	if kind != KindStruct && kind != KindKV {
+		return fmt.Errorf("invalid parser kind %d for %q", kind, "."+ext)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	registry["."+ext] = entry{parser: p, kind: kind}
+	delete(fieldsRegistry, "."+ext)
+	delete(layered, "."+ext)
+	return nil
+}
+
+// UnsupportedFormatError reports a file whose extension has no registered
+// parser.
+type UnsupportedFormatError struct {
+	// Path is the file path as passed to Load.
+	Path string
+	// Ext is its extension, including the dot; empty when it has none.
+	Ext string
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("unsupported file format: %q", e.Ext)
+}
+
+// layered lists the built-in KindKV formats whose parser expands references
+// against the values of earlier files: Load passes those in the target map
+// instead of a nil map.
+var layered = map[string]bool{".env": true}
+
+// Layered reports whether the parser for the extension of path takes the
+// values of the KindKV files loaded before it in its target map.
+func Layered(path string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return layered[filepath.Ext(path)]
+}
+
+// Snapshot saves the parser registry and returns a function restoring it,
+// undoing every Register call made in between. Tests use it to keep their
+// registrations from leaking into other tests.
+func Snapshot() (restore func()) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, f, l := maps.Clone(registry), maps.Clone(fieldsRegistry), maps.Clone(layered)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		registry, fieldsRegistry, layered = r, f, l
+	}
 }
 
 // Lookup returns the FileParser and Kind registered for the extension of path.
-// Returns an error if the extension is not supported.
-func Lookup(path string) (FileParser, Kind, error) {
+// When strict is true and the format has a strict variant, that variant is
+// returned instead. Returns an *UnsupportedFormatError if the extension is not
+// supported.
+func Lookup(path string, strict bool) (FileParser, Kind, error) {
+	mu.RLock()
 	e, ok := registry[filepath.Ext(path)]
+	mu.RUnlock()
 	if !ok {
-		return nil, 0, fmt.Errorf("unsupported file format: %q", filepath.Ext(path))
+		return nil, 0, &UnsupportedFormatError{Path: path, Ext: filepath.Ext(path)}
+	}
+	if strict && e.strict != nil {
+		return e.strict, e.kind, nil
 	}
 	return e.parser, e.kind, nil
 }
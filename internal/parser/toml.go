@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// TOML unmarshals TOML-encoded data into target, which must be a pointer to
+// a struct.
+//
+// Keys are matched to fields by the toml tag, falling back to the yaml tag
+// and then to the field name (case-insensitive), so a struct already tagged
+// for YAML reads TOML without extra tags. Decoding itself is delegated to the
+// YAML decoder, which keeps value conversion identical across both formats
+// (e.g. time.Duration is written as a string such as "30s").
+var TOML FileParser = func(data []byte, target any) error {
+	var raw map[string]any
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(renameKeys(raw, reflect.TypeOf(target).Elem()))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(out, target)
+}
+
+// renameKeys rewrites the keys of m to the names the YAML decoder expects for
+// the fields of struct type t, recursing into nested structs and into slices
+// and maps of structs. Keys with no matching field are kept as is.
+func renameKeys(m map[string]any, t reflect.Type) map[string]any {
+	out := make(map[string]any, len(m))
+	for key, val := range m {
+		f, ok := tomlField(t, key)
+		if !ok {
+			out[key] = val
+			continue
+		}
+		out[yamlName(f)] = renameValue(val, f.Type)
+	}
+	return out
+}
+
+func renameValue(val any, t reflect.Type) any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := val.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			return renameKeys(v, t)
+		case reflect.Map:
+			for k := range v {
+				v[k] = renameValue(v[k], t.Elem())
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i := range v {
+				v[i] = renameValue(v[i], t.Elem())
+			}
+		}
+	case []map[string]any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i := range v {
+				v[i] = renameValue(v[i], t.Elem()).(map[string]any)
+			}
+		}
+	}
+	return val
+}
+
+// tomlField finds the exported field of t that key refers to: an exact toml
+// tag match first, then an exact yaml tag match, then a case-insensitive
+// field name match for fields without either tag.
+func tomlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for _, lookup := range []func(reflect.StructField) bool{
+		func(f reflect.StructField) bool { return tagName(f, "toml") == key },
+		func(f reflect.StructField) bool { return tagName(f, "toml") == "" && tagName(f, "yaml") == key },
+		func(f reflect.StructField) bool {
+			return tagName(f, "toml") == "" && tagName(f, "yaml") == "" && strings.EqualFold(f.Name, key)
+		},
+	} {
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && lookup(f) {
+				return f, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tagName returns the name part of the struct tag key on f, or "" when the
+// tag is absent or has no name.
+func tagName(f reflect.StructField, key string) string {
+	name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+	return name
+}
+
+// yamlName returns the key the YAML decoder uses for f: its yaml tag name,
+// or the lowercased field name.
+func yamlName(f reflect.StructField) string {
+	if name := tagName(f, "yaml"); name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
@@ -0,0 +1,191 @@
+package validation
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// conditionalRequired lists the rules that, like required, report a missing
+// value; check skips the remaining rules of a field once one fails.
+var conditionalRequired = map[string]bool{
+	"required_if":      true,
+	"required_with":    true,
+	"required_without": true,
+}
+
+// presenceRules lists the rules that look at whether a field is set rather
+// than at its value, so they see a pointer field as the pointer itself.
+var presenceRules = map[string]bool{
+	"required_if":      true,
+	"required_with":    true,
+	"required_without": true,
+	"excluded_with":    true,
+}
+
+// requiredIfRule requires f to be set when every named field has the given
+// value: "Enabled true", or "Backend redis Mode cluster" for several fields.
+func requiredIfRule(f Field, param string) error {
+	args := strings.Fields(param)
+	if len(args) == 0 || len(args)%2 != 0 {
+		return usagef("needs field and value pairs")
+	}
+	var conds []string
+	for i := 0; i < len(args); i += 2 {
+		other, err := lookup(f, args[i])
+		if err != nil {
+			return err
+		}
+		if formatValue(other) != args[i+1] {
+			return nil
+		}
+		conds = append(conds, args[i]+" is "+args[i+1])
+	}
+	return requireSet(f, "when "+strings.Join(conds, " and "))
+}
+
+// requiredWithRule requires f to be set when any of the space-separated named
+// fields is set.
+func requiredWithRule(f Field, param string) error {
+	set, names, err := anySet(f, param, true)
+	if err != nil || !set {
+		return err
+	}
+	return requireSet(f, "when "+names+" is set")
+}
+
+// requiredWithoutRule requires f to be set when any of the space-separated
+// named fields is not set.
+func requiredWithoutRule(f Field, param string) error {
+	unset, names, err := anySet(f, param, false)
+	if err != nil || !unset {
+		return err
+	}
+	return requireSet(f, "when "+names+" is not set")
+}
+
+// excludedWithRule requires f to be unset when any of the space-separated
+// named fields is set.
+func excludedWithRule(f Field, param string) error {
+	set, names, err := anySet(f, param, true)
+	if err != nil || !set || f.Value.IsZero() {
+		return err
+	}
+	return fmt.Errorf("must not be set when %s is set", names)
+}
+
+// requireSet fails with a message ending in when if f is zero. Like required,
+// it is not supported on bool fields.
+func requireSet(f Field, when string) error {
+	if f.Value.Kind() == reflect.Bool {
+		return usagef("not supported on bool fields")
+	}
+	if f.Value.IsZero() {
+		return errors.New("must be set " + when)
+	}
+	return nil
+}
+
+// anySet reports whether any of the fields named in param is set, or for
+// set false, whether any is not set. It also returns the names for messages.
+func anySet(f Field, param string, set bool) (bool, string, error) {
+	names := strings.Fields(param)
+	if len(names) == 0 {
+		return false, "", usagef("needs at least one field")
+	}
+	found := false
+	for _, name := range names {
+		other, err := lookup(f, name)
+		if err != nil {
+			return false, "", err
+		}
+		if other.IsZero() != set {
+			found = true
+		}
+	}
+	return found, strings.Join(names, " or "), nil
+}
+
+// fieldOrderRule returns a Rule that compares a number or duration with the
+// field named in its parameter, which must have the same type, and fails
+// unless ok accepts the comparison.
+func fieldOrderRule(relation string, ok func(c int) bool) Rule {
+	return func(f Field, param string) error {
+		if param == "" {
+			return usagef("needs a field")
+		}
+		other, err := lookup(f, param)
+		if err != nil {
+			return err
+		}
+		if other.Kind() == reflect.Ptr && other.Type().Elem() == f.Value.Type() {
+			if other.IsNil() {
+				return nil
+			}
+			other = other.Elem()
+		}
+		if other.Type() != f.Value.Type() {
+			return usagef("field %s is %s, not %s", param, other.Type(), f.Value.Type())
+		}
+		var c int
+		switch f.Value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			c = cmp.Compare(f.Value.Int(), other.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			c = cmp.Compare(f.Value.Uint(), other.Uint())
+		case reflect.Float32, reflect.Float64:
+			c = cmp.Compare(f.Value.Float(), other.Float())
+		default:
+			return usagef("not supported on %s fields", f.Value.Type())
+		}
+		if !ok(c) {
+			return fmt.Errorf("must be %s %s (%s), got %s",
+				relation, param, formatValue(other), formatValue(f.Value))
+		}
+		return nil
+	}
+}
+
+// lookup returns the field named by a cross-field rule, or a usage error if
+// there is none.
+func lookup(f Field, name string) (reflect.Value, error) {
+	v, ok := f.Lookup(name)
+	if !ok {
+		return reflect.Value{}, usagef("no field %s", name)
+	}
+	return v, nil
+}
+
+// formatValue formats a field value for comparison with rule parameters and
+// for messages: numbers and bools as strconv writes them, durations as
+// time.Duration does, and strings as they are. Pointers are formatted as the
+// value they point to, and as "" when nil.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return v.Type().String()
+	}
+}
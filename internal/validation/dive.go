@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dive applies rules to each element of the slice or array f, named
+// "Brokers[2]", or to each value of the map f, named "Labels[env]". For maps,
+// rules may open with keys, ..., endkeys to check the keys as well:
+// "dive,keys,hostname,endkeys,url". It returns an error, without checking
+// anything, if f cannot be dived into.
+func (v *validator) dive(f Field, rules []string) error {
+	switch f.Value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if len(rules) > 0 && strings.TrimSpace(rules[0]) == "keys" {
+			return errors.New("keys is only supported on maps")
+		}
+		for i := 0; i < f.Value.Len(); i++ {
+			v.check(f.element(strconv.Itoa(i), f.Value.Index(i)), rules)
+		}
+		return nil
+	case reflect.Map:
+		keyRules, valueRules, err := splitKeyRules(rules)
+		if err != nil {
+			return err
+		}
+		keys := f.Value.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(formatValue(a), formatValue(b))
+		})
+		for _, k := range keys {
+			if keyRules != nil {
+				v.check(f.element(formatValue(k), k), keyRules)
+			}
+			v.check(f.element(formatValue(k), f.Value.MapIndex(k)), valueRules)
+		}
+		return nil
+	default:
+		return errors.New("not supported on " + f.Value.Type().String() + " fields")
+	}
+}
+
+// element returns the element of f at index, which shares the parent and root
+// of f so that cross-field rules resolve the same names.
+func (f Field) element(index string, value reflect.Value) Field {
+	return Field{Path: f.Path + "[" + index + "]", Value: value, Parent: f.Parent, Root: f.Root}
+}
+
+// splitKeyRules splits the rules after a dive on a map into the rules for
+// its keys, between keys and endkeys, and the rules for its values. keyRules
+// is nil when the keys are not checked.
+func splitKeyRules(rules []string) (keyRules, valueRules []string, err error) {
+	if len(rules) == 0 || strings.TrimSpace(rules[0]) != "keys" {
+		return nil, rules, nil
+	}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule) == "endkeys" {
+			return rules[1:i:i], rules[i+1:], nil
+		}
+	}
+	return nil, nil, errors.New("keys without endkeys")
+}
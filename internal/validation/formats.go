@@ -0,0 +1,184 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+)
+
+// formatRule returns a Rule that checks string fields with check. Empty
+// strings pass, so that optional fields can carry a format; combine with
+// required to demand a value.
+func formatRule(check func(s string) error) Rule {
+	return func(f Field, param string) error {
+		if param != "" {
+			return usagef("takes no parameter")
+		}
+		if f.Value.Kind() != reflect.String {
+			return usagef("not supported on %s fields", f.Value.Type())
+		}
+		if f.Value.String() == "" {
+			return nil
+		}
+		return check(f.Value.String())
+	}
+}
+
+// checkURL requires an absolute URL with a scheme and a host, such as
+// "https://example.com/path".
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be a URL with a scheme and host, got %q", s)
+	}
+	return nil
+}
+
+// checkHostname requires an RFC 1123 hostname: dot-separated labels of
+// letters, digits and hyphens, each at most 63 characters and not starting or
+// ending with a hyphen. A trailing dot is allowed.
+func checkHostname(s string) error {
+	if !isHostname(s) {
+		return fmt.Errorf("must be a hostname, got %q", s)
+	}
+	return nil
+}
+
+func isHostname(s string) bool {
+	if len(s) > 0 && s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	label := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.':
+			if label == 0 || s[i-1] == '-' {
+				return false
+			}
+			label = 0
+			continue
+		case c == '-':
+			if label == 0 {
+				return false
+			}
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		default:
+			return false
+		}
+		if label++; label > 63 {
+			return false
+		}
+	}
+	return s[len(s)-1] != '-'
+}
+
+// checkIP requires an IPv4 or IPv6 address.
+func checkIP(s string) error {
+	if _, err := netip.ParseAddr(s); err != nil {
+		return fmt.Errorf("must be an IP address, got %q", s)
+	}
+	return nil
+}
+
+// checkCIDR requires an IP prefix in CIDR notation, such as "10.0.0.0/8".
+func checkCIDR(s string) error {
+	if _, err := netip.ParsePrefix(s); err != nil {
+		return fmt.Errorf("must be a CIDR prefix such as 10.0.0.0/8, got %q", s)
+	}
+	return nil
+}
+
+// checkHostPort requires a host and port such as "db:5432" or "[::1]:8080".
+// The host may be empty, as in ":8080", to listen on every interface.
+func checkHostPort(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || !isPort(port) {
+		return fmt.Errorf("must be host:port, got %q", s)
+	}
+	if host != "" && !isHostname(host) {
+		if _, err := netip.ParseAddr(host); err != nil {
+			return fmt.Errorf("must be host:port, got %q", s)
+		}
+	}
+	return nil
+}
+
+// isPort reports whether s is a port number between 1 and 65535.
+func isPort(s string) bool {
+	n, err := strconv.ParseUint(s, 10, 16)
+	return err == nil && n > 0
+}
+
+// portRule requires a port number between 1 and 65535, held in an integer or
+// a string field. Zero values pass, as for the other format rules.
+func portRule(f Field, param string) error {
+	switch f.Value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if param != "" {
+			return usagef("takes no parameter")
+		}
+		if got := formatValue(f.Value); !f.Value.IsZero() && !isPort(got) {
+			return fmt.Errorf("must be a port between 1 and 65535, got %s", got)
+		}
+		return nil
+	}
+	return formatRule(func(s string) error {
+		if !isPort(s) {
+			return fmt.Errorf("must be a port between 1 and 65535, got %q", s)
+		}
+		return nil
+	})(f, param)
+}
+
+// checkEmail requires a bare email address such as "ops@example.com", without
+// a display name or angle brackets.
+func checkEmail(s string) error {
+	a, err := mail.ParseAddress(s)
+	if err != nil || a.Address != s {
+		return fmt.Errorf("must be an email address, got %q", s)
+	}
+	return nil
+}
+
+// checkFile requires the path of an existing regular file.
+func checkFile(s string) error {
+	info, err := os.Stat(s)
+	if err != nil {
+		return fmt.Errorf("must be an existing file: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("must be a regular file, got %q", s)
+	}
+	return nil
+}
+
+// checkDir requires the path of an existing directory.
+func checkDir(s string) error {
+	info, err := os.Stat(s)
+	if err != nil {
+		return fmt.Errorf("must be an existing directory: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("must be a directory, got %q", s)
+	}
+	return nil
+}
+
+// checkAbsDir requires the absolute path of an existing directory.
+func checkAbsDir(s string) error {
+	if !filepath.IsAbs(s) {
+		return fmt.Errorf("must be an absolute path, got %q", s)
+	}
+	return checkDir(s)
+}
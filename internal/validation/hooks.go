@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// selfValidator is implemented by configuration structs that check
+// themselves once their validate tags pass.
+type selfValidator interface {
+	Validate() error
+}
+
+// ValidateMethods calls the Validate method of the struct c points to and of
+// every nested struct that has one, nested structs before the structs that
+// hold them, and returns their errors as *FieldError. A struct is not called
+// when one of its nested structs failed, since its checks may rely on theirs.
+// Structs isLeaf reports as set as a whole are values, not configuration, and
+// are not called.
+func ValidateMethods(c any, isLeaf func(reflect.Type) bool) []error {
+	var errs []error
+	callValidate(reflect.ValueOf(c).Elem(), "", isLeaf, &errs)
+	return errs
+}
+
+// callValidate calls Validate on the nested structs of s and then on s
+// itself, whose dotted path is path. It reports whether every call passed.
+func callValidate(s reflect.Value, path string, isLeaf func(reflect.Type) bool, errs *[]error) bool {
+	ok := true
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() != reflect.Struct || !field.IsExported() || isLeaf(field.Type) {
+			continue
+		}
+		nested := field.Name
+		if path != "" {
+			nested = path + "." + field.Name
+		}
+		if !callValidate(s.Field(i), nested, isLeaf, errs) {
+			ok = false
+		}
+	}
+	if !ok {
+		return false
+	}
+	v, has := s.Addr().Interface().(selfValidator)
+	if !has {
+		return true
+	}
+	err := v.Validate()
+	if err == nil {
+		return true
+	}
+	if path != "" {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	*errs = append(*errs, &FieldError{Path: path, Err: err})
+	return false
+}
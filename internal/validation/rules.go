@@ -0,0 +1,287 @@
+package validation
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Field is a field under validation.
+type Field struct {
+	// Path is the dotted path of the field, e.g. "DB.Port".
+	Path string
+	// Value is the field's value.
+	Value reflect.Value
+	// Parent is the struct holding the field.
+	Parent reflect.Value
+	// Root is the configuration struct passed to Validate.
+	Root reflect.Value
+}
+
+// Lookup returns the field that a cross-field rule names: a sibling of f for
+// a plain name such as "Enabled", or for a dotted name such as
+// "Cache.Backend", the field at that path from the root struct.
+func (f Field) Lookup(name string) (reflect.Value, bool) {
+	v := f.Parent
+	if strings.Contains(name, ".") {
+		v = f.Root
+	}
+	for _, part := range strings.Split(name, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		if v = v.FieldByName(part); !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// Rule checks a field against a rule's parameter — the text after "=" in the
+// validate tag, or "" — and returns an error saying how the value breaks the
+// rule, such as "must be at least 1024, got 80", or nil. A built-in rule
+// that cannot apply to the field's type or to param returns an error from
+// usagef.
+type Rule func(f Field, param string) error
+
+// rules holds the rules available in validate tags, apart from required. mu
+// guards it against Register.
+var (
+	mu    sync.RWMutex
+	rules = map[string]Rule{
+		"min":     minRule,
+		"max":     maxRule,
+		"len":     lenRule,
+		"oneof":   oneofRule,
+		"pattern": patternRule,
+
+		"url":      formatRule(checkURL),
+		"hostname": formatRule(checkHostname),
+		"ip":       formatRule(checkIP),
+		"cidr":     formatRule(checkCIDR),
+		"hostport": formatRule(checkHostPort),
+		"port":     portRule,
+		"email":    formatRule(checkEmail),
+		"file":     formatRule(checkFile),
+		"dir":      formatRule(checkDir),
+		"absdir":   formatRule(checkAbsDir),
+
+		"required_if":      requiredIfRule,
+		"required_with":    requiredWithRule,
+		"required_without": requiredWithoutRule,
+		"excluded_with":    excludedWithRule,
+		"gtfield":          fieldOrderRule("greater than", func(c int) bool { return c > 0 }),
+		"gtefield":         fieldOrderRule("at least", func(c int) bool { return c >= 0 }),
+		"ltfield":          fieldOrderRule("less than", func(c int) bool { return c < 0 }),
+		"ltefield":         fieldOrderRule("at most", func(c int) bool { return c <= 0 }),
+	}
+)
+
+// Register makes r available in validate tags as name, replacing any rule
+// already registered under it — including the built-in ones, apart from
+// required, dive, keys and endkeys. Register is safe for concurrent use with
+// Validate.
+func Register(name string, r Rule) error {
+	if name == "" || strings.ContainsAny(name, "=, \t") {
+		return fmt.Errorf("invalid validator name %q", name)
+	}
+	if name == "required" || name == "dive" || name == "keys" || name == "endkeys" {
+		return fmt.Errorf("validator name %q is reserved", name)
+	}
+	if r == nil {
+		return fmt.Errorf("validator %q must not be nil", name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rules[name] = r
+	return nil
+}
+
+// Snapshot saves the rule registry and returns a function restoring it,
+// undoing every Register call made in between. Tests use it to keep their
+// registrations from leaking into other tests.
+func Snapshot() (restore func()) {
+	mu.RLock()
+	defer mu.RUnlock()
+	saved := maps.Clone(rules)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		rules = saved
+	}
+}
+
+// lookupRule returns the rule registered as name.
+func lookupRule(name string) (Rule, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+// usageError reports a rule used on a field type or with a parameter it does
+// not support — a mistake in the struct tags, not in the configuration.
+type usageError struct{ msg string }
+
+func (e *usageError) Error() string { return e.msg }
+
+// usagef returns a *usageError with a formatted message.
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// minRule requires a number or duration of at least param, or a string,
+// slice or map with at least param characters or elements.
+func minRule(f Field, param string) error {
+	c, err := compare(f.Value, param)
+	if err != nil || c.result >= 0 {
+		return err
+	}
+	if c.unit != "" {
+		return fmt.Errorf("must have at least %s, got %s", quantity(param, c.unit), c.got)
+	}
+	return fmt.Errorf("must be at least %s, got %s", param, c.got)
+}
+
+// maxRule is the counterpart of minRule.
+func maxRule(f Field, param string) error {
+	c, err := compare(f.Value, param)
+	if err != nil || c.result <= 0 {
+		return err
+	}
+	if c.unit != "" {
+		return fmt.Errorf("must have at most %s, got %s", quantity(param, c.unit), c.got)
+	}
+	return fmt.Errorf("must be at most %s, got %s", param, c.got)
+}
+
+// lenRule requires a string, slice or map with exactly param characters or
+// elements.
+func lenRule(f Field, param string) error {
+	c, err := compare(f.Value, param)
+	if err != nil {
+		return err
+	}
+	if c.unit == "" {
+		return usagef("not supported on %s fields", f.Value.Type())
+	}
+	if c.result != 0 {
+		return fmt.Errorf("must have exactly %s, got %s", quantity(param, c.unit), c.got)
+	}
+	return nil
+}
+
+// comparison is the result of comparing a field with a rule parameter.
+type comparison struct {
+	result int    // -1, 0 or +1 as the field is below, at or above param
+	unit   string // "character" or "element" for lengths, "" for values
+	got    string // the compared quantity, for messages
+}
+
+// compare compares v with param: by value for numbers and time.Duration —
+// param is then a duration such as "5s" — and by length for strings (in
+// characters), slices, arrays and maps.
+func compare(v reflect.Value, param string) (comparison, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return comparison{}, usagef("invalid duration %q", param)
+		}
+		got := time.Duration(v.Int())
+		return comparison{result: cmp.Compare(got, d), got: got.String()}, nil
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return comparison{}, usagef("invalid integer %q", param)
+		}
+		return comparison{result: cmp.Compare(v.Int(), p), got: strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return comparison{}, usagef("invalid unsigned integer %q", param)
+		}
+		return comparison{result: cmp.Compare(v.Uint(), p), got: strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return comparison{}, usagef("invalid number %q", param)
+		}
+		return comparison{result: cmp.Compare(v.Float(), p), got: strconv.FormatFloat(v.Float(), 'g', -1, 64)}, nil
+	case reflect.String:
+		return compareLen(utf8.RuneCountInString(v.String()), param, "character")
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return compareLen(v.Len(), param, "element")
+	default:
+		return comparison{}, usagef("not supported on %s fields", v.Type())
+	}
+}
+
+// quantity returns n followed by unit, pluralized unless n is 1.
+func quantity(n, unit string) string {
+	if n == "1" {
+		return n + " " + unit
+	}
+	return n + " " + unit + "s"
+}
+
+func compareLen(n int, param, unit string) (comparison, error) {
+	p, err := strconv.Atoi(param)
+	if err != nil || p < 0 {
+		return comparison{}, usagef("invalid length %q", param)
+	}
+	return comparison{result: cmp.Compare(n, p), unit: unit, got: strconv.Itoa(n)}, nil
+}
+
+// oneofRule requires a string or integer equal to one of the space-separated
+// values of param.
+func oneofRule(f Field, param string) error {
+	options := strings.Fields(param)
+	if len(options) == 0 {
+		return usagef("needs at least one value")
+	}
+	var got, quoted string
+	switch f.Value.Kind() {
+	case reflect.String:
+		got = f.Value.String()
+		quoted = strconv.Quote(got)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		got = formatValue(f.Value)
+		quoted = got
+	default:
+		return usagef("not supported on %s fields", f.Value.Type())
+	}
+	for _, o := range options {
+		if got == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s, got %s", strings.Join(options, ", "), quoted)
+}
+
+// patternRule requires a string matching the regular expression param. The
+// match is unanchored; use ^ and $ to match the whole value.
+func patternRule(f Field, param string) error {
+	if f.Value.Kind() != reflect.String {
+		return usagef("not supported on %s fields", f.Value.Type())
+	}
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return usagef("invalid pattern: %v", err)
+	}
+	if !re.MatchString(f.Value.String()) {
+		return fmt.Errorf("must match pattern %q, got %q", param, f.Value.String())
+	}
+	return nil
+}
@@ -1,43 +1,113 @@
 // Package validation provides struct validation for gonphig configuration
-// structs. It is intentionally minimal: the only supported rule is "required",
-// which checks that a field is not the zero value for its type.
+// structs. Fields are checked against the comma-separated rules of their
+// validate tag: "required", which checks that a field is not the zero value
+// for its type, the value rules in rules.go, the format rules in formats.go
+// and the cross-field rules in crossfield.go.
 package validation
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 )
 
-// ValidateRequired inspects c for fields tagged validate:"required" and
-// returns an error for the first field whose value is the zero value for its
-// type (e.g. "" for string, 0 for numeric types).
+// FieldError is a failure to load or validate a single configuration field.
+// Load joins every FieldError it finds into one error.
+type FieldError struct {
+	// Path is the dotted path of the field, e.g. "DB.Port".
+	Path string
+	// Key is the env var — prefix included — or, for fields without an env
+	// tag, the flag name the field is read from. It is empty when the field
+	// has neither.
+	Key string
+	// Err describes the failure.
+	Err error
+}
+
+func (e *FieldError) Error() string { return e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// MissingFieldError reports a field tagged validate:"required" that is still
+// zero once every source has been applied.
+type MissingFieldError struct {
+	// Path is the dotted path of the field, e.g. "DB.Port".
+	Path string
+	// Key is the env var or flag name that would set the field, as for
+	// FieldError.
+	Key string
+}
+
+func (e *MissingFieldError) Error() string {
+	return "missing required configuration: " + e.Path
+}
+
+// RuleError reports a field whose value breaks a validate rule, such as
+// min=1024 on a port of 80.
+type RuleError struct {
+	// Path is the dotted path of the field, e.g. "DB.Port".
+	Path string
+	// Key is the env var or flag name that sets the field, as for FieldError.
+	Key string
+	// Rule is the name of the rule, e.g. "min".
+	Rule string
+	// Param is the rule's parameter, e.g. "1024"; empty for rules without one.
+	Param string
+	// Msg says how the value breaks the rule, e.g. "must be at least 1024,
+	// got 80".
+	Msg string
+}
+
+func (e *RuleError) Error() string { return e.Path + ": " + e.Msg }
+
+// Validate checks every field of c against the rules in its validate tag and
+// returns one error per failure, in field order, or nil when c is valid:
 //
-// validate:"required" is not supported on bool fields — false is a valid value
-// that cannot be distinguished from unset. Using it on a bool returns an error.
+//   - a *MissingFieldError for a required field that is still the zero value
+//     for its type (e.g. "" for string, 0 for numeric types)
+//   - a *RuleError for a value that breaks a rule such as min or oneof, or a
+//     cross-field rule such as required_if
+//   - a *FieldError for a rule that is unknown, or that cannot apply to the
+//     field's type or parameter
 //
-// Unknown rules in the validate tag (e.g. validate:"min=1") return an error
-// immediately so typos fail loudly rather than silently skipping validation.
+// validate:"required" is not supported on bool fields — false is a valid value
+// that cannot be distinguished from unset. Using it on a bool is an error; a
+// *bool, nil until a source sets it, can be required.
+// Unknown rules (e.g. validate:"requried") are errors too, so typos fail
+// loudly rather than silently skipping validation.
 //
-// c must be a non-nil pointer to a struct. ValidateRequired recurses into
-// nested structs automatically.
+// c must be a non-nil pointer to a struct. Validate recurses into nested
+// structs automatically, except those isLeaf reports as set as a whole, such
+// as netip.Addr, which are checked like any other field.
 //
-// Error format: "missing required configuration: <FieldName>"
-func ValidateRequired(c any) error {
-	return walk(reflect.TypeOf(c).Elem(), reflect.ValueOf(c).Elem())
+// Errors name fields by their full dotted path, so two nested fields with the
+// same name are told apart: "missing required configuration: DB.Primary.URL".
+func Validate(c any, isLeaf func(reflect.Type) bool) []error {
+	root := reflect.ValueOf(c).Elem()
+	v := validator{root: root, isLeaf: isLeaf}
+	v.walk(root, "")
+	return v.errs
 }
 
-// walk traverses t/v field-by-field, recursing into nested structs, and
-// checks the validate tag on each non-struct field.
-func walk(t reflect.Type, v reflect.Value) error {
+// validator collects the failures of one Validate call.
+type validator struct {
+	root   reflect.Value
+	isLeaf func(reflect.Type) bool
+	errs   []error
+}
+
+// walk traverses the struct s field-by-field, recursing into nested structs
+// that are not leaves, and checks the validate tag on each non-struct field.
+// prefix is the dotted path of s, ending in "." unless s is the root.
+func (v *validator) walk(s reflect.Value, prefix string) {
+	t := s.Type()
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		value := v.Field(i)
+		path := prefix + field.Name
 
-		if field.Type.Kind() == reflect.Struct {
-			if err := walk(field.Type, value); err != nil {
-				return err
-			}
+		if field.Type.Kind() == reflect.Struct && !v.isLeaf(field.Type) {
+			v.walk(s.Field(i), path+".")
 			continue
 		}
 
@@ -45,26 +115,102 @@ func walk(t reflect.Type, v reflect.Value) error {
 		if !ok {
 			continue
 		}
+		v.check(Field{Path: path, Value: s.Field(i), Parent: s, Root: v.root}, splitRules(tag))
+	}
+}
 
-		for _, rule := range strings.Split(tag, ",") {
-			switch strings.TrimSpace(rule) {
-			case "required":
-				if field.Type.Kind() == reflect.Bool {
-					return fmt.Errorf(
-						"validate:\"required\" is not supported on bool field %s — "+
-							"false is a valid value that cannot be distinguished from unset",
-						field.Name,
-					)
-				}
-				if value.IsZero() {
-					return fmt.Errorf("missing required configuration: %s", field.Name)
+// check applies rules to f, stopping after a failed required or conditional
+// required rule since the remaining rules would only restate that the field
+// is empty. The rules after a dive apply to the elements of f instead.
+//
+// A pointer field is set when it is non-nil: required and the presence rules
+// look at the pointer, and the other rules at the value it points to, which
+// they skip when it is nil.
+func (v *validator) check(f Field, rules []string) {
+	fail := func(err error) {
+		v.errs = append(v.errs, &FieldError{Path: f.Path, Err: err})
+	}
+	elem, isNil := f, false
+	if f.Value.Kind() == reflect.Ptr {
+		if isNil = f.Value.IsNil(); !isNil {
+			elem.Value = f.Value.Elem()
+		}
+	}
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "dive":
+			if isNil {
+				return
+			}
+			if err := v.dive(elem, rules[i+1:]); err != nil {
+				fail(fmt.Errorf("validate:\"dive\" on field %s: %s", f.Path, err))
+			}
+			return
+		case "required":
+			if f.Value.Kind() == reflect.Bool {
+				fail(fmt.Errorf(
+					"validate:\"required\" is not supported on bool field %s — "+
+						"false is a valid value that cannot be distinguished from unset; use *bool",
+					f.Path,
+				))
+				continue
+			}
+			if f.Value.IsZero() {
+				v.errs = append(v.errs, &MissingFieldError{Path: f.Path})
+				return
+			}
+		case "":
+			// ignore empty segments from trailing commas
+		default:
+			check, ok := lookupRule(name)
+			if !ok {
+				fail(fmt.Errorf("unknown validation rule %q on field %s", name, f.Path))
+				continue
+			}
+			target := f
+			if !presenceRules[name] {
+				if isNil {
+					continue
 				}
-			case "":
-				// ignore empty segments from trailing commas
+				target = elem
+			}
+			err := check(target, param)
+			var usage *usageError
+			switch {
+			case err == nil:
+			case errors.As(err, &usage):
+				fail(fmt.Errorf("validate:%q on field %s: %s", strings.TrimSpace(rule), f.Path, usage.msg))
 			default:
-				return fmt.Errorf("unknown validation rule %q on field %s", strings.TrimSpace(rule), field.Name)
+				v.errs = append(v.errs, &RuleError{Path: f.Path, Rule: name, Param: param, Msg: err.Error()})
+				if conditionalRequired[name] {
+					return
+				}
+			}
+		}
+	}
+}
+
+// splitRules splits a validate tag on commas, except for commas nested in
+// brackets, braces or parentheses, so that patterns such as ^[a-z]{1,3}$
+// stay whole.
+func splitRules(tag string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(tag); i++ {
+		switch tag[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				out = append(out, tag[start:i])
+				start = i + 1
 			}
 		}
 	}
-	return nil
+	return append(out, tag[start:])
 }
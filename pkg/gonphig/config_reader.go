@@ -1,75 +1,124 @@
 // Package gonphig loads configuration from multiple sources into a typed Go
-// struct using struct tags. Sources are merged in a fixed priority order:
-// CLI flags (highest) → environment variables → .env file → struct tag
-// defaults → YAML file (lowest).
+// struct using struct tags. By default, sources are merged in this priority
+// order (WithPrecedence changes it):
+// CLI flags (highest) → environment variables → .env file → YAML, TOML or
+// JSON file → struct tag defaults (lowest). Defaults are a fallback: they only
+// apply to fields no other source set, so an explicit zero value in a file
+// (port: 0, enabled: false) is kept.
 //
 // .env files are resolved via the env struct tag — fields without an env tag
 // are not reachable from a .env file.
 //
 // The single entry point is Load. Environment variables and struct tag
-// defaults are always considered. Additional sources — YAML files and CLI
-// flags — are enabled via options.
+// defaults are always considered. Additional sources — YAML, TOML and JSON
+// files and CLI flags — are enabled via options, and WithSource plugs in
+// custom ones.
 //
 // # Supported field types
 //
-// string, int, int64, float32, float64, bool, time.Duration, and []string are
-// supported. []string values are read as comma-separated strings from env vars
-// and the default tag. time.Duration values accept any string understood by
-// time.ParseDuration (e.g. "5s", "1m30s"). In YAML, always use the string
-// form — a bare integer zero (timeout: 0) is rejected; write timeout: 0s.
+// string, bool, float32, float64, every signed and unsigned integer kind
+// (int, int8 … int64, uint, uint8 … uint64) and time.Duration are supported,
+// along with slices of them. Integers are parsed in base 10 at the size of
+// the field, so a value that overflows it is an error rather than wrapping
+// around. Slices are read as comma-separated values from env vars and the
+// default tag, each entry parsed as the element type; a []byte instead holds
+// the raw value, like a string. time.Duration values accept any string
+// understood by time.ParseDuration (e.g. "5s", "1m30s"). In YAML, always use
+// the string form — a bare integer zero (timeout: 0) is rejected; write
+// timeout: 0s.
+//
+// A type whose pointer implements encoding.TextUnmarshaler or flag.Value,
+// such as slog.Level or netip.Addr, parses itself through UnmarshalText, or
+// failing that Set, instead of by its kind. A struct among them is a single
+// value, not nested configuration. RegisterDecoder and WithDecoder make any
+// other type loadable with a parse function, ahead of both.
+//
+// A pointer to a supported type is optional: it stays nil unless a source
+// sets it, so validate:"required" can tell a *bool set to false from unset.
 //
 // # Struct tags
 //
 //   - flag:"name"         bind to a CLI flag (requires WithFlags or WithArgs)
 //   - flag-usage:"txt"    usage string shown in --help (optional, use with flag)
 //   - env:"VAR"           bind to an environment variable or .env key
+//   - env-prefix:"P"      on a nested struct field, prefix the env keys of its
+//     fields with P_ (stacks with WithEnvPrefix and outer env-prefix tags)
 //   - default:"val"       fallback when no higher-priority source sets the field
-//   - validate:"required" return an error if the field is zero after loading
+//   - validate:"rules"    comma-separated rules checked once all sources are
+//     applied: required (an error if the field is zero), min, max, len, oneof,
+//     pattern, formats such as url and hostport, cross-field rules such as
+//     required_if and gtfield, and dive to check slice and map elements.
+//     RegisterValidator adds custom rules, and a Validate() error method on
+//     the struct or a nested struct is called after the rules pass
 //   - yaml:"name"         rename the field when reading from a YAML file
+//   - toml:"name"         rename the field when reading from a TOML file (falls
+//     back to the yaml tag, then the field name)
+//   - json:"name"         rename the field when reading from a JSON file
 //
 // Tags may be combined freely on the same field.
 package gonphig
 
 import (
+	"encoding"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/m-sossich/gonphig/internal/parser"
 	"github.com/m-sossich/gonphig/internal/validation"
+	"maps"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	readEnvKey  = "env"
-	readFlagKey = "flag"
-	defaultKey  = "default"
-	flagUsage   = "flag-usage"
+	readEnvKey   = "env"
+	envPrefixKey = "env-prefix"
+	readFlagKey  = "flag"
+	defaultKey   = "default"
+	flagUsage    = "flag-usage"
 )
 
-// Option configures Load. Options are created by WithFile, WithArgs, WithFlags,
-// and WithEnvPrefix.
+// Option configures Load. Options are created by the With functions of this
+// package, such as WithFile, WithFlags and WithStrict.
 type Option func(*settings)
 
 type settings struct {
-	filePath string
-	hasFile  bool
-	fs       *flag.FlagSet
-	args     []string
-	hasFlags bool
+	files      []string
+	fs         *flag.FlagSet
+	args       []string
+	hasFlags   bool
+	envPrefix  string
+	strict     bool
+	expand     bool
+	precedence []SourceID
+	sources    []customSource
+	report     *Report
+	decoders   map[reflect.Type]decoder
 }
 
 // WithFile enables a file as a configuration source, dispatching to the
 // appropriate parser based on the file extension (.yml/.yaml for YAML,
-// .env for dotenv). File values are the lowest-priority source — they are
-// overridden by env vars and flags.
+// .toml for TOML, .json for JSON, .env for dotenv). File values are
+// overridden by env vars and flags, and override struct tag defaults.
+//
+// WithFile may be passed more than once to load files in layers. Files are
+// applied in the order given and later files override earlier ones: YAML,
+// TOML and JSON layers are deep-merged, so a later file only replaces the
+// keys it sets (including keys of nested maps), and .env layers override
+// earlier .env keys.
+//
+//	gonphig.Load(&cfg,
+//		gonphig.WithFile("config.yml"),
+//		gonphig.WithFile("config.prod.yml"),
+//		gonphig.WithFile(".env"),
+//	)
 func WithFile(path string) Option {
 	return func(s *settings) {
-		s.filePath = path
-		s.hasFile = true
+		s.files = append(s.files, path)
 	}
 }
 
@@ -100,6 +149,50 @@ func WithFlags(fs *flag.FlagSet, args []string) Option {
 	}
 }
 
+// WithStrict makes file parsing fail on input that would otherwise be
+// dropped silently, so a broken config fails Load instead of misbehaving:
+//
+//   - a JSON key without a matching struct field is an error
+//   - a .env line without "=", an invalid key name, a key assigned twice, or
+//     text after a closing quote is an error naming the file, line number and
+//     offending text
+func WithStrict() Option {
+	return func(s *settings) {
+		s.strict = true
+	}
+}
+
+// WithExpand expands variable references — $VAR, ${VAR}, ${VAR:-default}
+// and ${VAR:?message} — in default tags and in string values read from YAML,
+// TOML and JSON files. References resolve against real environment
+// variables first, then keys from .env files passed via WithFile. Names are
+// used as written; WithEnvPrefix does not apply to them. A file value is
+// expanded only when the file wins its field, so a reference that cannot
+// resolve is harmless once a higher-priority source overrides it; one that
+// matters fails Load with a *ParseError.
+//
+// .env files always expand references in their own values; WithExpand is only
+// needed for defaults and structured files.
+func WithExpand() Option {
+	return func(s *settings) {
+		s.expand = true
+	}
+}
+
+// WithEnvPrefix prefixes every env tag lookup — real environment variables and
+// .env keys alike — with prefix followed by an underscore. With
+// WithEnvPrefix("BILLING"), a field tagged env:"HOST" reads BILLING_HOST.
+// A trailing underscore in prefix is not doubled.
+//
+// Nested structs may extend the prefix with an env-prefix tag on the struct
+// field; with WithEnvPrefix("BILLING") and env-prefix:"DB", env:"HOST" on a
+// field of that struct reads BILLING_DB_HOST.
+func WithEnvPrefix(prefix string) Option {
+	return func(s *settings) {
+		s.envPrefix = prefix
+	}
+}
+
 // Bootstrap loads configuration into c exactly like Load, but panics on error.
 // Intended for use in main functions where a config failure is unrecoverable.
 //
@@ -111,10 +204,13 @@ func Bootstrap(c any, opts ...Option) {
 }
 
 // Load reads configuration into c from all enabled sources, applying them in
-// priority order: flags > env vars > .env file > struct tag defaults > file.
+// priority order: flags > env vars > .env file > file > struct tag defaults,
+// unless WithPrecedence sets another order. Sources added with WithSource are
+// ranked among them by priority.
 //
 // Environment variables and struct tag defaults are always considered.
-// Additional sources are enabled via WithFile, WithArgs, and WithFlags.
+// Additional sources are enabled via WithFile, WithArgs, WithFlags and
+// WithSource.
 // Use WithEnvPrefix to apply a common prefix to all env var lookups.
 //
 // c must be a non-nil pointer to a struct. Passing nil, a non-pointer, or a
@@ -122,7 +218,16 @@ func Bootstrap(c any, opts ...Option) {
 // (e.g. chan, func) return an error at load time.
 //
 // If any field is tagged validate:"required" and its value remains zero after
-// all sources are applied, Load returns an error.
+// all sources are applied, or breaks one of its other validate rules, Load
+// returns an error.
+//
+// Errors are typed, so callers can branch on them with errors.As. Load does
+// not stop at the first invalid field: every per-field failure — a
+// *ParseError, *MissingFieldError, *RuleError, *UnsupportedTypeError or
+// *FieldError — is collected, and all of them are joined into the returned
+// error (see errors.Join). Problems that prevent loading altogether, such as
+// an *InvalidTargetError or *UnsupportedFormatError, are returned on their
+// own.
 func Load(c any, opts ...Option) error {
 	if err := validateInput(c); err != nil {
 		return err
@@ -131,32 +236,44 @@ func Load(c any, opts ...Option) error {
 	if err != nil {
 		return err
 	}
-	l := &loader{fs: s.fs}
-	if err := l.loadFile(c, s); err != nil {
+	sources, err := rankSources(s)
+	if err != nil {
 		return err
 	}
-	if err := l.applyFields(c); err != nil {
+	l := &loader{
+		fs:            s.fs,
+		sources:       sources,
+		envPrefix:     s.envPrefix,
+		expand:        s.expand,
+		decoders:      s.decoders,
+		decodedFields: make(map[string]bool),
+		resolved:      make(map[string]bool),
+		unexpanded:    make(map[string]error),
+		flagFields:    make(map[string]flagField),
+		keys:          make(map[string]string),
+		failed:        make(map[string]bool),
+	}
+	if s.report != nil {
+		l.report = make(map[string][]origin)
+		defer func() { *s.report = l.buildReport() }()
+	}
+	if err := l.loadSources(c); err != nil {
 		return err
 	}
-	if err := s.parseFlags(); err != nil {
+	l.applyFields(c)
+	if err := l.applyFlags(); err != nil {
 		return err
 	}
-	return validation.ValidateRequired(c)
+	for _, path := range slices.Sorted(maps.Keys(l.unexpanded)) {
+		l.fail(path, l.keys[path], l.unexpanded[path])
+	}
+	return l.validate(c)
 }
 
 func validateInput(c any) error {
-	if c == nil {
-		return errors.New("configuration must not be nil")
-	}
-	switch reflect.TypeOf(c).Kind() {
-	case reflect.Ptr:
-		if reflect.TypeOf(c).Elem().Kind() != reflect.Struct {
-			return errors.New("invalid configuration structure")
-		}
-	case reflect.Struct:
-		return errors.New("configuration to load needs to be a pointer")
-	default:
-		return errors.New("invalid configuration structure")
+	t := reflect.TypeOf(c)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return &InvalidTargetError{Type: t}
 	}
 	return nil
 }
@@ -169,271 +286,783 @@ func buildSettings(opts []Option) (*settings, error) {
 	if s.hasFlags && s.fs == nil {
 		return nil, errors.New("flag set must not be nil")
 	}
+	for t, d := range s.decoders {
+		if d == nil {
+			return nil, fmt.Errorf("decoder for %s must not be nil", t)
+		}
+	}
+	if s.precedence == nil {
+		s.precedence = defaultPrecedence
+	} else if err := validatePrecedence(s.precedence); err != nil {
+		return nil, err
+	}
 	if !s.hasFlags {
 		s.fs = flag.NewFlagSet("", flag.ContinueOnError)
 	}
 	return s, nil
 }
 
-func (s *settings) parseFlags() error {
-	if !s.hasFlags {
-		return nil
+// loadSources loads every source except flags and defaults, from the lowest
+// priority up, so that Decode sources layer over each other in order and
+// later .env files override earlier ones.
+func (l *loader) loadSources(c any) error {
+	for i := len(l.sources) - 1; i >= 0; i-- {
+		src := l.sources[i]
+		if src.id == SourceFlags || src.id == SourceDefault {
+			continue
+		}
+		if err := src.load(); err != nil {
+			return err
+		}
+		if src.data.Decode == nil {
+			if src.id == SourceDotEnv {
+				l.addDotEnv(src.data.Values)
+			}
+			continue
+		}
+		set, err := decodeLayer(src.data.Decode, c, l.isLeaf)
+		if err != nil {
+			return err
+		}
+		src.fields = set
+		for field := range set {
+			l.decodedFields[field] = true
+		}
+		if l.report != nil {
+			src.raw = formatFields(reflect.ValueOf(c).Elem(), set)
+		}
 	}
-	return s.fs.Parse(s.args)
+	return nil
 }
 
-func (l *loader) loadFile(c any, s *settings) error {
-	if !s.hasFile {
-		return nil
+// addDotEnv records the values of a .env file for variable expansion.
+func (l *loader) addDotEnv(vars map[string]string) {
+	if l.dotenvVars == nil {
+		l.dotenvVars = make(map[string]string, len(vars))
 	}
-	parse, kind, err := parser.Lookup(s.filePath)
-	if err != nil {
-		return err
+	for k, v := range vars {
+		l.dotenvVars[k] = v
 	}
-	data, err := os.ReadFile(s.filePath)
+}
+
+// decodeLayer runs decode on c, on top of the values left by lower-priority
+// sources, and returns the paths of the fields it sets. Struct fields merge
+// naturally because decoders only touch the keys they hold. Map fields are
+// merged key by key afterwards, recursing into nested maps, so a layer never
+// drops entries it does not mention.
+//
+// When decode reports no presence — custom parsers and sources may not — a
+// field counts as set when decoding changed its value, which cannot see a
+// layer that repeats the current value. isLeaf tells which structs are values
+// compared as a whole.
+func decodeLayer(decode func(any) (map[string]string, error), c any, isLeaf func(reflect.Type) bool) (map[string]string, error) {
+	rv := reflect.ValueOf(c).Elem()
+	prev := reflect.New(rv.Type()).Elem()
+	prev.Set(rv)
+	cloneMaps(prev)
+	set, err := decode(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	switch kind {
-	case parser.KindStruct:
-		return parse(data, c)
-	case parser.KindKV:
-		return parse(data, &l.dotenvVars)
+	if set == nil {
+		set = make(map[string]string)
+		changedFields(prev, rv, "", isLeaf, set)
 	}
-	return nil
+	mergeMaps(rv, prev)
+	return set, nil
 }
 
-func (l *loader) applyFields(c any) error {
-	rv := reflect.ValueOf(c).Elem()
-	rt := reflect.TypeOf(c).Elem()
-	for i := 0; i < rt.NumField(); i++ {
-		value := rv.Field(i)
-		if err := l.overwriteFields(rt.Field(i), &value); err != nil {
-			return err
+// changedFields records in out the path of every leaf field of the struct
+// after whose value differs from before. The key is unknown and left empty.
+func changedFields(before, after reflect.Value, prefix string, isLeaf func(reflect.Type) bool, out map[string]string) {
+	for i := 0; i < after.NumField(); i++ {
+		f := after.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		path := prefix + f.Name
+		if f.Type.Kind() == reflect.Struct && !isLeaf(f.Type) {
+			changedFields(before.Field(i), after.Field(i), path+".", isLeaf, out)
+			continue
+		}
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			out[path] = ""
 		}
 	}
-	return nil
 }
 
-// loader carries per-Load context (FlagSet, dotenv values) so it does not
-// need to be threaded through every setter function signature.
-type loader struct {
-	fs         *flag.FlagSet
-	dotenvVars map[string]string
+// cloneMaps replaces every map field of the struct v, at any depth of
+// nesting, with a shallow copy. Parsers may decode into an existing map in
+// place, so decodeLayer snapshots maps before decoding a new layer.
+func cloneMaps(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			cloneMaps(field)
+		case reflect.Map:
+			if field.IsNil() {
+				continue
+			}
+			clone := reflect.MakeMapWithSize(field.Type(), field.Len())
+			iter := field.MapRange()
+			for iter.Next() {
+				clone.SetMapIndex(iter.Key(), iter.Value())
+			}
+			field.Set(clone)
+		}
+	}
 }
 
-// applyTagSources resolves the default and env tag sources for v using parse.
-// The default is applied only when v is zero; env always wins when the variable
-// resolves to a non-empty string. Default parse errors are silently ignored;
-// env parse errors are returned.
-func (l *loader) applyTagSources(v *reflect.Value, t reflect.StructTag, parse func(string) error) error {
-	if v.IsZero() {
-		if def, ok := t.Lookup(defaultKey); ok && def != "" {
-			_ = parse(def)
+// mergeMaps walks the struct v alongside prev, its state before the latest
+// layer was decoded, and merges each map field of prev into the matching
+// field of v.
+func mergeMaps(v, prev reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		switch field := v.Field(i); field.Kind() {
+		case reflect.Struct:
+			mergeMaps(field, prev.Field(i))
+		case reflect.Map:
+			mergeMap(field, prev.Field(i))
 		}
 	}
-	if key, ok := t.Lookup(readEnvKey); ok {
-		if raw := l.getenv(key); raw != "" {
-			if err := parse(raw); err != nil {
+}
+
+// mergeMap copies every key of prev missing from cur into cur. When both
+// hold a map for the same key, the two are merged recursively. A nil cur
+// means the layer explicitly reset the map and is left alone.
+func mergeMap(cur, prev reflect.Value) {
+	if cur.IsNil() || prev.IsNil() {
+		return
+	}
+	iter := prev.MapRange()
+	for iter.Next() {
+		cv := cur.MapIndex(iter.Key())
+		if !cv.IsValid() {
+			cur.SetMapIndex(iter.Key(), iter.Value())
+			continue
+		}
+		cm, pm := unwrapInterface(cv), unwrapInterface(iter.Value())
+		if cm.Kind() == reflect.Map && pm.Kind() == reflect.Map && cm.Type() == pm.Type() {
+			mergeMap(cm, pm)
+		}
+	}
+}
+
+func unwrapInterface(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Interface {
+		return v.Elem()
+	}
+	return v
+}
+
+// expandStrings applies expand to every string reachable from v: string
+// fields at any depth of struct nesting, and strings held in slices, arrays,
+// maps and interfaces. Values of other kinds are left untouched.
+func expandStrings(v reflect.Value, expand func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := expand(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return expandStrings(v.Elem(), expand)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := expandStrings(v.Field(i), expand); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandStrings(v.Index(i), expand); err != nil {
 				return err
 			}
 		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// The dynamic value is not addressable; expand a copy and store it back.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := expandStrings(elem, expand); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			if err := expandStrings(elem, expand); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
 	}
 	return nil
 }
 
-// applyField applies all tag sources (default, env, flag) to v.
-// parse handles default and env values; registerFlag wires up the CLI flag
-// using the current field value — already resolved from lower-priority sources
-// — as the flag default.
-func (l *loader) applyField(v *reflect.Value, t reflect.StructTag, parse func(string) error, registerFlag func(name, usage string)) error {
-	if err := l.applyTagSources(v, t, parse); err != nil {
-		return err
+func (l *loader) applyFields(c any) {
+	rv := reflect.ValueOf(c).Elem()
+	rt := reflect.TypeOf(c).Elem()
+	for i := 0; i < rt.NumField(); i++ {
+		value := rv.Field(i)
+		l.overwriteFields(rt.Field(i), &value)
 	}
-	if name, ok := t.Lookup(readFlagKey); ok {
-		registerFlag(name, getUsage(t))
+	if !l.expand {
+		return
+	}
+	// Fields without tags, maps and slices of structs are never resolved:
+	// the Decode sources are all that set them.
+	for _, path := range slices.Sorted(maps.Keys(l.decodedFields)) {
+		if l.resolved[path] {
+			continue
+		}
+		i := slices.IndexFunc(l.sources, func(src *rankedSource) bool {
+			_, ok := src.fields[path]
+			return ok && src.data.Decode != nil
+		})
+		if v, ok := fieldByPath(rv, path); ok && i >= 0 {
+			o := origin{src: i, key: l.sources[i].fields[path]}
+			if err := l.expandDecoded(v, path, o); err != nil {
+				l.fail(path, l.keys[path], err)
+			}
+		}
 	}
-	return nil
 }
 
-// getenv looks up key in the environment, falling back to .env file values.
-func (l *loader) getenv(key string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+// fail records err for the field at path, read under key. Errors without a
+// type of their own are recorded as a *FieldError.
+func (l *loader) fail(path, key string, err error) {
+	switch err.(type) {
+	case *ParseError, *UnsupportedTypeError:
+	default:
+		err = &FieldError{Path: path, Key: key, Err: err}
 	}
-	return l.dotenvVars[key]
+	l.errs = append(l.errs, err)
+	l.failed[path] = true
 }
 
-// durationType is used to detect time.Duration fields by named type before
-// the reflect.Int64 case in the kind switch, since Duration's Kind() is Int64.
-var durationType = reflect.TypeOf(time.Duration(0))
+// validate runs the validate tags on c and returns every error recorded
+// during the Load joined together. Validation errors on fields that already
+// failed to load are dropped, since they only restate that failure; the rest
+// get the key of their field, or for slice and map elements, of the field
+// holding them. Validate methods run only once everything else passed, so
+// they can rely on the tags.
+func (l *loader) validate(c any) error {
+	for _, err := range validation.Validate(c, l.isLeaf) {
+		if path, key := errorField(err); key != nil {
+			if l.failed[fieldPath(path)] {
+				continue
+			}
+			*key = l.keys[fieldPath(path)]
+		}
+		l.errs = append(l.errs, err)
+	}
+	if len(l.errs) == 0 {
+		l.errs = validation.ValidateMethods(c, l.isLeaf)
+	}
+	return errors.Join(l.errs...)
+}
 
-// overwriteFields applies default, env, and flag tags to a single struct
-// field in that order. It recurses into nested structs. Parse errors are
-// wrapped with the field name so callers can identify which field failed.
-func (l *loader) overwriteFields(f reflect.StructField, v *reflect.Value) error {
-	if f.Type == durationType {
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setDuration(v, t)
-		}))
+// errorField returns the path a validation error is reported on and its Key
+// field, or a nil key for errors without one.
+func errorField(err error) (path string, key *string) {
+	switch e := err.(type) {
+	case *MissingFieldError:
+		return e.Path, &e.Key
+	case *RuleError:
+		return e.Path, &e.Key
+	case *FieldError:
+		return e.Path, &e.Key
 	}
+	return "", nil
+}
 
-	switch f.Type.Kind() {
-	case reflect.Struct:
-		for i := 0; i < f.Type.NumField(); i++ {
-			value := v.Field(i)
-			if err := l.overwriteFields(f.Type.Field(i), &value); err != nil {
-				return err
+// fieldPath returns the path of the field holding the element at path, such
+// as "Brokers" for "Brokers[2]", or path itself for a field.
+func fieldPath(path string) string {
+	if i := strings.IndexByte(path, '['); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// loader carries per-Load context (FlagSet, ranked sources, dotenv values,
+// fields set by Decode sources, resolved fields, expansion failures a flag
+// may still override, flag-bound fields, origins for the report,
+// field keys and errors, current env prefix and field path) so it does not
+// need to be threaded through every setter function signature.
+type loader struct {
+	fs            *flag.FlagSet
+	sources       []*rankedSource
+	dotenvVars    map[string]string
+	decodedFields map[string]bool
+	resolved      map[string]bool
+	unexpanded    map[string]error
+	flagFields    map[string]flagField
+	report        map[string][]origin
+	decoders      map[reflect.Type]decoder
+	keys          map[string]string
+	errs          []error
+	failed        map[string]bool
+	envPrefix     string
+	path          string
+	expand        bool
+}
+
+// origin is the value a source holds for a field: the index of the source
+// in l.sources, the key it is held under and its raw value.
+type origin struct {
+	src int
+	key string
+	raw string
+}
+
+// resolve finds the highest-ranked source, other than flags, that sets the
+// current field and returns its value. Flags are applied later, once the
+// FlagSet is parsed. A field set by a Decode source resolves to it with no
+// raw value to parse, since the source already decoded it in place; a field
+// no source sets resolves to src -1.
+//
+// Key-value sources count only when the value is non-empty. The default
+// counts when the tag is non-empty and the field is either set by a Decode
+// source or still zero, so a value the caller stored before Load is not
+// clobbered.
+//
+// With WithProvenance, resolve goes on past the winner and records every
+// source that sets the field.
+func (l *loader) resolve(v *reflect.Value, t reflect.StructTag) (origin, error) {
+	l.resolved[l.path] = true
+	var found []origin
+	for i := range l.sources {
+		if len(found) > 0 && l.report == nil {
+			break
+		}
+		o, ok, err := l.lookup(i, v, t)
+		if err != nil {
+			if len(found) > 0 {
+				// An overridden default that fails to expand is irrelevant.
+				break
 			}
+			return o, err
 		}
-	case reflect.Int64:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setInt64(v, t)
-		}))
-	case reflect.Int:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setInt(v, t)
-		}))
-	case reflect.Float32:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setFloat32(v, t)
-		}))
-	case reflect.Float64:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setFloat64(v, t)
-		}))
-	case reflect.String:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setString(v, t)
-		}))
-	case reflect.Bool:
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setBool(v, t)
-		}))
-	case reflect.Slice:
-		if f.Type.Elem().Kind() != reflect.String {
-			return nil
+		if ok {
+			found = append(found, o)
 		}
-		return l.wrap(f.Name, overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
-			return l.setStringSlice(v, t)
-		}))
-	case reflect.Map:
-		return nil
+	}
+	if len(found) == 0 {
+		return origin{src: -1}, nil
+	}
+	if l.report != nil {
+		l.report[l.path] = found
+	}
+	return found[0], nil
+}
+
+// lookup returns the value the source at index i holds for the current field
+// and whether it sets the field at all.
+func (l *loader) lookup(i int, v *reflect.Value, t reflect.StructTag) (origin, bool, error) {
+	src := l.sources[i]
+	switch {
+	case src.id == SourceFlags:
+	case src.id == SourceDefault:
+		if !l.decodedFields[l.path] && !v.IsZero() {
+			return origin{}, false, nil
+		}
+		def, err := l.defaultValue(t)
+		if err != nil {
+			return origin{}, false, &ParseError{Path: l.path, Source: src.src.Name(), Raw: t.Get(defaultKey), Err: err}
+		}
+		return origin{src: i, raw: def}, def != "", nil
+	case src.data.Decode != nil:
+		key, ok := src.fields[l.path]
+		return origin{src: i, key: key, raw: src.raw[l.path]}, ok, nil
 	default:
-		return fmt.Errorf("invalid field[%s] type[%s]", f.Name, f.Type.Name())
+		if key, ok := l.sourceKey(t, src.data.Tag); ok && src.data.Values[key] != "" {
+			return origin{src: i, key: key, raw: src.data.Values[key]}, true, nil
+		}
 	}
-	return nil
+	return origin{}, false, nil
+}
+
+// sourceKey returns the key a field reads from a key-value source keyed by
+// tag. Env keys carry the current env prefix.
+func (l *loader) sourceKey(t reflect.StructTag, tag string) (string, bool) {
+	key, ok := t.Lookup(tag)
+	if ok && tag == readEnvKey {
+		key = joinEnvKey(l.envPrefix, key)
+	}
+	return key, ok
+}
+
+// applyTagSources resolves every source but flags for v and applies the
+// winner using parse. It returns the index of the winning source, or -1.
+// Default parse errors are silently ignored; key-value parse errors and
+// expansion errors are returned as a *ParseError. A Decode source that wins
+// a flag-tagged field may still be outranked by the flag, so its expansion
+// error is held in l.unexpanded until flags are applied.
+func (l *loader) applyTagSources(v *reflect.Value, t reflect.StructTag, parse func(string) error) (int, error) {
+	o, err := l.resolve(v, t)
+	if err != nil || o.src < 0 {
+		return o.src, err
+	}
+	switch src := l.sources[o.src]; {
+	case src.id == SourceDefault:
+		_ = parse(o.raw)
+	case src.data.Decode == nil:
+		if err := parse(o.raw); err != nil {
+			return o.src, l.parseError(l.path, o, err)
+		}
+	default:
+		if err := l.expandDecoded(*v, l.path, o); err != nil {
+			if _, ok := t.Lookup(readFlagKey); !ok {
+				return o.src, err
+			}
+			l.unexpanded[l.path] = err
+		}
+	}
+	return o.src, nil
 }
 
-// wrap annotates err with the field name, making parse failures actionable.
-func (l *loader) wrap(fieldName string, err error) error {
+// expandDecoded expands, with WithExpand, the strings of the field v at path
+// that the Decode source in o set. A reference that fails to expand is
+// returned as a *ParseError holding the string it appears in.
+func (l *loader) expandDecoded(v reflect.Value, path string, o origin) error {
+	if !l.expand {
+		return nil
+	}
+	var raw string
+	err := expandStrings(v, func(s string) (string, error) {
+		raw = s
+		return l.expandValue(s)
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", fieldName, err)
+		o.raw = raw
+		return l.parseError(path, o, err)
 	}
 	return nil
 }
 
-// overwriteValue calls setValue only when the field has at least one struct
-// tag defined, skipping untagged fields entirely.
-func overwriteValue(tag reflect.StructTag, v *reflect.Value, setValue func(*reflect.Value, reflect.StructTag) error) error {
-	if len(tag) > 0 {
-		return setValue(v, tag)
+// parseError reports that the raw value o holds for the field at path failed
+// to parse.
+func (l *loader) parseError(path string, o origin, err error) *ParseError {
+	return &ParseError{Path: path, Source: l.sources[o.src].src.Name(), Key: o.key, Raw: o.raw, Err: err}
+}
+
+// applyField applies all sources to v, using parse for raw values. A flag tag
+// registers a flag whose default is the value just resolved from the other
+// sources; applyFlags applies it once the FlagSet is parsed.
+func (l *loader) applyField(v *reflect.Value, t reflect.StructTag, parse valueParser) error {
+	set := func(s string) error { return parse(*v, s) }
+	from, err := l.applyTagSources(v, t, set)
+	if err != nil {
+		return err
+	}
+	if name, ok := t.Lookup(readFlagKey); ok {
+		l.registerFlag(*v, name, getUsage(t), parse)
+		l.flagFields[name] = flagField{v: *v, path: l.path, parse: set, from: from}
 	}
 	return nil
 }
 
-func (l *loader) setString(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { v.SetString(strings.TrimSpace(s)); return nil },
-		func(name, usage string) { l.fs.StringVar(v.Addr().Interface().(*string), name, v.String(), usage) },
-	)
+// flagField is a flag-bound field along with the source it resolved to
+// before flags were parsed.
+type flagField struct {
+	v     reflect.Value
+	path  string
+	parse func(string) error
+	from  int
 }
 
-func (l *loader) setBool(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseBool(v, s) },
-		func(name, usage string) { l.fs.BoolVar(v.Addr().Interface().(*bool), name, v.Bool(), usage) },
-	)
+// applyFlags loads the flag source and applies every flag passed whose source
+// outranks the one its field resolved to. Invalid flag values already fail
+// the FlagSet's Parse, whose error is returned as is, so that its error
+// handling mode applies; a value that still fails to parse into its field is
+// recorded as a field error. An outranked flag is parsed too, and the field
+// restored afterwards, so invalid input fails Load either way.
+func (l *loader) applyFlags() error {
+	idx := slices.IndexFunc(l.sources, func(src *rankedSource) bool { return src.id == SourceFlags })
+	if idx < 0 {
+		return nil
+	}
+	if err := l.sources[idx].load(); err != nil {
+		return err
+	}
+	vars := l.sources[idx].data.Values
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		f, ok := l.flagFields[name]
+		if !ok {
+			continue
+		}
+		saved := reflect.New(f.v.Type()).Elem()
+		saved.Set(f.v)
+		o := origin{src: idx, key: name, raw: vars[name]}
+		if err := f.parse(o.raw); err != nil {
+			l.fail(f.path, name, l.parseError(f.path, o, err))
+			continue
+		}
+		if !l.outranks(idx, f.from) {
+			f.v.Set(saved)
+		} else {
+			delete(l.unexpanded, f.path)
+		}
+		if l.report != nil {
+			l.addOrigin(f.path, o)
+		}
+	}
+	return nil
 }
 
-func (l *loader) setInt64(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseInt64(v, s) },
-		func(name, usage string) { l.fs.Int64Var(v.Addr().Interface().(*int64), name, v.Int(), usage) },
-	)
+// defaultValue returns the default tag of t, expanded when WithExpand is set.
+// A failed expansion, such as an unset ${VAR:?message}, is returned as an
+// error.
+func (l *loader) defaultValue(t reflect.StructTag) (string, error) {
+	def := t.Get(defaultKey)
+	if !l.expand || def == "" {
+		return def, nil
+	}
+	return l.expandValue(def)
 }
 
-func (l *loader) setInt(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseInt64(v, s) },
-		func(name, usage string) { l.fs.IntVar(v.Addr().Interface().(*int), name, int(v.Int()), usage) },
-	)
+// expandValue expands the variable references in s against the environment
+// and loaded .env values.
+func (l *loader) expandValue(s string) (string, error) {
+	return parser.Expand(s, func(name string) (string, bool) {
+		if val := os.Getenv(name); val != "" {
+			return val, true
+		}
+		val, ok := l.dotenvVars[name]
+		return val, ok
+	})
 }
 
-// float32Flag implements flag.Value for float32 fields. The flag package has
-// no Float32Var, so a custom flag.Value is used instead. Unlike fs.XxxVar
-// which writes the default directly, fs.Var only reads String() for display —
-// so setFloat32 writes the default/env value to the field before registering
-// the flag, then String() reflects that value back for --help output.
-type float32Flag struct{ v *reflect.Value }
+// joinEnvKey joins an env prefix and key with an underscore. An empty prefix
+// leaves key unchanged, and a prefix already ending in "_" is not doubled.
+func joinEnvKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if strings.HasSuffix(prefix, "_") {
+		return prefix + key
+	}
+	return prefix + "_" + key
+}
 
-func (f float32Flag) String() string {
-	return strconv.FormatFloat(f.v.Float(), 'g', -1, 32)
+// joinFieldPath appends name to the dotted field path parent.
+func joinFieldPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
 }
 
-func (f float32Flag) Set(s string) error {
-	return parseFloat(f.v, s, 32)
+// durationType is time.Duration, which has a built-in decoder so that it is
+// not parsed as the int64 it is.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+)
+
+// parsesItself reports whether a pointer to t implements
+// encoding.TextUnmarshaler or flag.Value. Fields of such types are set through
+// that method rather than by kind, and never recursed into, even when t is a
+// struct such as netip.Addr.
+func parsesItself(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(textUnmarshalerType) || pt.Implements(flagValueType)
 }
 
-func (l *loader) setFloat32(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseFloat(v, s, 32) },
-		func(name, usage string) { l.fs.Var(float32Flag{v}, name, usage) },
-	)
+// isLeaf reports whether fields of type t are set as a whole, through a
+// decoder or their own parsing method, rather than recursed into as nested
+// configuration when t is a struct.
+func (l *loader) isLeaf(t reflect.Type) bool {
+	_, ok := l.decoderFor(t)
+	return ok || parsesItself(t)
 }
 
-func (l *loader) setFloat64(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseFloat(v, s, 64) },
-		func(name, usage string) { l.fs.Float64Var(v.Addr().Interface().(*float64), name, v.Float(), usage) },
-	)
+// overwriteFields applies every source to a single struct field. It recurses
+// into nested structs, extending the field path — and the env prefix when the
+// struct field carries an env-prefix tag — for the duration of the recursion.
+// A field that fails to load is recorded with its path and key, and the walk
+// goes on so that Load can report every failure at once.
+func (l *loader) overwriteFields(f reflect.StructField, v *reflect.Value) {
+	outer := l.path
+	l.path = joinFieldPath(outer, f.Name)
+	defer func() { l.path = outer }()
+
+	if f.Type.Kind() == reflect.Struct && !l.isLeaf(f.Type) {
+		if prefix, ok := f.Tag.Lookup(envPrefixKey); ok {
+			outer := l.envPrefix
+			l.envPrefix = joinEnvKey(outer, prefix)
+			defer func() { l.envPrefix = outer }()
+		}
+		for i := 0; i < f.Type.NumField(); i++ {
+			value := v.Field(i)
+			l.overwriteFields(f.Type.Field(i), &value)
+		}
+		return
+	}
+	key := l.fieldKey(f.Tag)
+	if key != "" {
+		l.keys[l.path] = key
+	}
+	if err := l.overwriteField(f, v); err != nil {
+		l.fail(l.path, key, err)
+	}
 }
 
-func (l *loader) setDuration(v *reflect.Value, t reflect.StructTag) error {
-	return l.applyField(v, t,
-		func(s string) error { return parseDuration(v, s) },
-		func(name, usage string) {
-			l.fs.DurationVar(v.Addr().Interface().(*time.Duration), name, time.Duration(v.Int()), usage)
-		},
-	)
+// fieldKey returns the key a field is read from: its env var, prefix
+// included, or else its flag name.
+func (l *loader) fieldKey(t reflect.StructTag) string {
+	if key, ok := l.sourceKey(t, readEnvKey); ok {
+		return key
+	}
+	return t.Get(readFlagKey)
 }
 
-func (l *loader) setStringSlice(v *reflect.Value, t reflect.StructTag) error {
-	if _, ok := t.Lookup(readFlagKey); ok {
-		return fmt.Errorf("flag tag is not supported for slice fields")
+// overwriteField applies the sources to a non-struct field, parsing raw values
+// with the parser for its type. Slices other than []byte, which holds the raw
+// value, are split and parsed entry by entry instead; maps, and slices of
+// types that cannot be parsed from a string, are left to file sources.
+func (l *loader) overwriteField(f reflect.StructField, v *reflect.Value) error {
+	if !l.isLeaf(f.Type) {
+		switch f.Type.Kind() {
+		case reflect.Slice:
+			if f.Type.Elem().Kind() == reflect.Uint8 {
+				break
+			}
+			parse, ok := l.parserFor(f.Type.Elem())
+			if !ok {
+				return nil
+			}
+			return overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
+				return l.setSlice(v, t, parse)
+			})
+		case reflect.Map:
+			return nil
+		}
 	}
-	raw := l.resolveSliceRaw(v, t)
-	if raw == "" {
-		return nil
+	parse, ok := l.parserFor(f.Type)
+	if !ok {
+		return &UnsupportedTypeError{Path: l.path, Type: f.Type}
+	}
+	return overwriteValue(f.Tag, v, func(v *reflect.Value, t reflect.StructTag) error {
+		return l.applyField(v, t, parse)
+	})
+}
+
+// overwriteValue calls setValue only when the field has at least one struct
+// tag defined, skipping untagged fields entirely.
+func overwriteValue(tag reflect.StructTag, v *reflect.Value, setValue func(*reflect.Value, reflect.StructTag) error) error {
+	if len(tag) > 0 {
+		return setValue(v, tag)
 	}
-	v.Set(reflect.ValueOf(splitTrimmed(raw)))
 	return nil
 }
 
-func (l *loader) resolveSliceRaw(v *reflect.Value, t reflect.StructTag) string {
-	if key, ok := t.Lookup(readEnvKey); ok {
-		if raw := l.getenv(key); raw != "" {
-			return raw
+// valueParser parses the raw value s into the settable value v.
+type valueParser func(v reflect.Value, s string) error
+
+// parserFor returns the parser for values of type t, or false if t cannot be
+// parsed from a string. Decoders come first, then types that parse
+// themselves, so that a named type such as time.Duration or slog.Level is not
+// handled by its kind. A pointer is parsed as the value it points to, which is
+// allocated only once it parsed, so that a pointer no source sets stays nil.
+func (l *loader) parserFor(t reflect.Type) (valueParser, bool) {
+	if d, ok := l.decoderFor(t); ok {
+		return func(v reflect.Value, s string) error {
+			val, err := d(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			v.Set(val)
+			return nil
+		}, true
+	}
+	if parsesItself(t) {
+		return parseText, true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return parseInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return parseUint, true
+	case reflect.Float32, reflect.Float64:
+		return parseFloat, true
+	case reflect.String:
+		return parseString, true
+	case reflect.Bool:
+		return parseBool, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return parseBytes, true
 		}
+	case reflect.Ptr:
+		parse, ok := l.parserFor(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return func(v reflect.Value, s string) error {
+			n := reflect.New(t.Elem())
+			if err := parse(n.Elem(), s); err != nil {
+				return err
+			}
+			v.Set(n)
+			return nil
+		}, true
+	}
+	return nil, false
+}
+
+// setSlice sets a slice field from a comma-separated raw value, parsing each
+// entry with parse. An entry that fails is reported as a *ParseError on its
+// index, e.g. "Ports[1]", with the entry as its Raw, and leaves the field
+// unset. Like other default parse errors, a default that fails is ignored.
+func (l *loader) setSlice(v *reflect.Value, t reflect.StructTag, parse valueParser) error {
+	if _, ok := t.Lookup(readFlagKey); ok {
+		return fmt.Errorf("%s: flag tag is not supported for slice fields", l.path)
+	}
+	o, err := l.resolve(v, t)
+	if err != nil || o.src < 0 {
+		return err
+	}
+	if l.sources[o.src].data.Decode != nil {
+		return l.expandDecoded(*v, l.path, o)
 	}
-	if v.IsNil() {
-		if def, ok := t.Lookup(defaultKey); ok {
-			return def
+	parts := splitTrimmed(o.raw)
+	s := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		if err := parse(s.Index(i), p); err != nil {
+			if l.sources[o.src].id == SourceDefault {
+				return nil
+			}
+			o.raw = p
+			return l.parseError(fmt.Sprintf("%s[%d]", l.path, i), o, err)
 		}
 	}
-	return ""
+	v.Set(s)
+	return nil
 }
 
+// splitTrimmed splits raw on commas, trimming each entry and dropping empty
+// ones.
 func splitTrimmed(raw string) []string {
 	parts := strings.Split(raw, ",")
 	result := make([]string, 0, len(parts))
@@ -445,7 +1074,18 @@ func splitTrimmed(raw string) []string {
 	return result
 }
 
-func parseBool(v *reflect.Value, val string) error {
+func parseString(v reflect.Value, val string) error {
+	v.SetString(strings.TrimSpace(val))
+	return nil
+}
+
+// parseBytes sets the []byte v to the bytes of val, trimmed like a string.
+func parseBytes(v reflect.Value, val string) error {
+	v.SetBytes([]byte(strings.TrimSpace(val)))
+	return nil
+}
+
+func parseBool(v reflect.Value, val string) error {
 	if trimmed := strings.TrimSpace(val); trimmed != "" {
 		parsed, err := strconv.ParseBool(trimmed)
 		if err != nil {
@@ -456,9 +1096,11 @@ func parseBool(v *reflect.Value, val string) error {
 	return nil
 }
 
-func parseInt64(v *reflect.Value, val string) error {
+// parseInt parses val into a signed integer of v's size, so that a value
+// that overflows it, such as 300 for an int8, fails with strconv.ErrRange.
+func parseInt(v reflect.Value, val string) error {
 	if trimmed := strings.TrimSpace(val); trimmed != "" {
-		parsed, err := strconv.ParseInt(trimmed, 10, 64)
+		parsed, err := strconv.ParseInt(trimmed, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
@@ -467,28 +1109,43 @@ func parseInt64(v *reflect.Value, val string) error {
 	return nil
 }
 
-// parseFloat parses val into a float of the given bitSize (32 or 64) and sets
-// it on v. Used by both setFloat32 and setFloat64 to avoid duplication.
-func parseFloat(v *reflect.Value, val string, bitSize int) error {
+// parseUint is the unsigned counterpart of parseInt. Negative values fail
+// with strconv.ErrSyntax.
+func parseUint(v reflect.Value, val string) error {
 	if trimmed := strings.TrimSpace(val); trimmed != "" {
-		parsed, err := strconv.ParseFloat(trimmed, bitSize)
+		parsed, err := strconv.ParseUint(trimmed, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
-		v.SetFloat(parsed)
+		v.SetUint(parsed)
 	}
 	return nil
 }
 
-func parseDuration(v *reflect.Value, val string) error {
-	d, err := time.ParseDuration(strings.TrimSpace(val))
-	if err != nil {
-		return err
+// parseFloat parses val into a float of v's size, 32 or 64 bits.
+func parseFloat(v reflect.Value, val string) error {
+	if trimmed := strings.TrimSpace(val); trimmed != "" {
+		parsed, err := strconv.ParseFloat(trimmed, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(parsed)
 	}
-	v.SetInt(int64(d))
 	return nil
 }
 
+// parseText parses val into a value whose type parses itself, through
+// UnmarshalText when a pointer to it implements encoding.TextUnmarshaler and
+// through Set otherwise.
+func parseText(v reflect.Value, val string) error {
+	switch p := v.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText([]byte(strings.TrimSpace(val)))
+	default:
+		return p.(flag.Value).Set(strings.TrimSpace(val))
+	}
+}
+
 func getUsage(tag reflect.StructTag) string {
 	val, _ := tag.Lookup(flagUsage)
 	return val
@@ -0,0 +1,101 @@
+package gonphig
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// decoder parses a raw string into a value of the type it was registered
+// for.
+type decoder func(s string) (reflect.Value, error)
+
+// decoderOf wraps fn as a decoder for T.
+func decoderOf[T any](fn func(string) (T, error)) decoder {
+	return func(s string) (reflect.Value, error) {
+		val, err := fn(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// Through a pointer, so that a nil interface T still yields a value.
+		return reflect.ValueOf(&val).Elem(), nil
+	}
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[reflect.Type]decoder{
+		durationType: decoderOf(time.ParseDuration),
+	}
+	// builtin lists the types whose global decoder is still the built-in one.
+	builtin = map[reflect.Type]bool{durationType: true}
+)
+
+// RegisterDecoder makes fields of type T loadable from env vars, .env files,
+// defaults and flags, parsing their raw string values with fn. It is meant for
+// types you cannot add an UnmarshalText method to:
+//
+//	func init() {
+//		if err := gonphig.RegisterDecoder(url.Parse); err != nil {
+//			panic(err)
+//		}
+//	}
+//
+// lets a *url.URL field read env:"ENDPOINT". Registering a type again
+// replaces its decoder — time.Duration's built-in one included. A decoder
+// takes precedence over the methods of encoding.TextUnmarshaler and
+// flag.Value, and over the handling of T's kind. Registration is global; use
+// WithDecoder for a single Load.
+func RegisterDecoder[T any](fn func(string) (T, error)) error {
+	t := reflect.TypeFor[T]()
+	if fn == nil {
+		return fmt.Errorf("decoder for %s must not be nil", t)
+	}
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[t] = decoderOf(fn)
+	delete(builtin, t)
+	return nil
+}
+
+// WithDecoder is RegisterDecoder for a single Load: fields of type T are
+// parsed with fn, ahead of any decoder registered globally for T.
+//
+//	gonphig.Load(&cfg, gonphig.WithDecoder(mail.ParseAddress))
+func WithDecoder[T any](fn func(string) (T, error)) Option {
+	return func(s *settings) {
+		if s.decoders == nil {
+			s.decoders = make(map[reflect.Type]decoder)
+		}
+		// A nil decoder is kept for buildSettings to reject.
+		var d decoder
+		if fn != nil {
+			d = decoderOf(fn)
+		}
+		s.decoders[reflect.TypeFor[T]()] = d
+	}
+}
+
+// decoderFor returns the decoder for fields of type t: the one passed to
+// WithDecoder, else the one registered globally.
+func (l *loader) decoderFor(t reflect.Type) (decoder, bool) {
+	if d, ok := l.decoders[t]; ok {
+		return d, true
+	}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, ok := decoders[t]
+	return d, ok
+}
+
+// builtinDecoder reports whether fields of type t are parsed by a built-in
+// decoder, one neither WithDecoder nor RegisterDecoder replaced.
+func (l *loader) builtinDecoder(t reflect.Type) bool {
+	if _, ok := l.decoders[t]; ok {
+		return false
+	}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	return builtin[t]
+}
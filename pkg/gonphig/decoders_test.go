@@ -0,0 +1,176 @@
+package gonphig
+
+import (
+	"errors"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotDecoders saves the global decoders and returns a function
+// restoring them, for tests that call RegisterDecoder.
+func snapshotDecoders() func() {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, b := maps.Clone(decoders), maps.Clone(builtin)
+	return func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		decoders, builtin = d, b
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	t.Cleanup(snapshotDecoders())
+	require.NoError(t, RegisterDecoder(url.Parse))
+
+	type testType struct {
+		Endpoint *url.URL `env:"ENDPOINT" validate:"required"`
+		Fallback *url.URL `default:"http://localhost:8080"`
+		Proxy    *url.URL `flag:"proxy"`
+	}
+	t.Setenv("ENDPOINT", " https://api.example.com/v1 ")
+
+	var config testType
+	err := Load(&config, WithFlags(newFlagSet(t.Name()), []string{"--proxy=http://proxy:3128"}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/v1", config.Endpoint.String())
+	assert.Equal(t, "http://localhost:8080", config.Fallback.String())
+	assert.Equal(t, "http://proxy:3128", config.Proxy.String())
+}
+
+func TestRegisterDecoderError(t *testing.T) {
+	t.Cleanup(snapshotDecoders())
+	require.NoError(t, RegisterDecoder(url.Parse))
+
+	type testType struct {
+		Endpoint *url.URL `env:"ENDPOINT"`
+	}
+	t.Setenv("ENDPOINT", "http://[::1")
+
+	var config testType
+	err := Load(&config)
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, "Endpoint", pe.Path)
+	assert.Equal(t, "http://[::1", pe.Raw)
+	var ue *url.Error
+	assert.True(t, errors.As(err, &ue))
+}
+
+// hostPort is a type with no decoder of its own.
+type hostPort struct {
+	host string
+	port int
+}
+
+func parseHostPort(s string) (*hostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	return &hostPort{host: host, port: p}, err
+}
+
+func TestWithDecoder(t *testing.T) {
+	type testType struct {
+		Primary *hostPort `env:"PRIMARY" default:"localhost:5432"`
+		Replica *hostPort `env:"REPLICA"`
+	}
+	t.Setenv("PRIMARY", "db:6432")
+
+	var config testType
+	require.NoError(t, Load(&config, WithDecoder(parseHostPort)))
+	assert.Equal(t, &hostPort{host: "db", port: 6432}, config.Primary)
+	assert.Nil(t, config.Replica)
+
+	var other testType
+	err := Load(&other)
+	var ute *UnsupportedTypeError
+	require.True(t, errors.As(err, &ute), "WithDecoder only applies to its own Load")
+}
+
+func TestWithDecoderOverridesRegistered(t *testing.T) {
+	type testType struct {
+		Timeout time.Duration `env:"TIMEOUT"`
+	}
+	t.Setenv("TIMEOUT", "90")
+
+	var config testType
+	require.Error(t, Load(&config), "the built-in duration decoder needs a unit")
+
+	seconds := func(s string) (time.Duration, error) {
+		d, err := time.ParseDuration(s)
+		if err != nil && !strings.ContainsAny(s, "smhµnu") {
+			d, err = time.ParseDuration(s + "s")
+		}
+		return d, err
+	}
+	require.NoError(t, Load(&config, WithDecoder(seconds)))
+	assert.Equal(t, 90*time.Second, config.Timeout)
+}
+
+func TestDecodedStructIsValidatedAsAField(t *testing.T) {
+	parse := func(s string) (url.URL, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	}
+	type testType struct {
+		Endpoint url.URL `env:"ENDPOINT" validate:"required"`
+	}
+
+	var config testType
+	err := Load(&config, WithDecoder(parse))
+	var mfe *MissingFieldError
+	require.True(t, errors.As(err, &mfe), "a decoded struct is not walked into")
+	assert.Equal(t, "Endpoint", mfe.Path)
+
+	t.Setenv("ENDPOINT", "https://api.example.com")
+	require.NoError(t, Load(&config, WithDecoder(parse)))
+	assert.Equal(t, "api.example.com", config.Endpoint.Host)
+}
+
+// celsius is a named type whose kind, float64, would otherwise decide how it
+// is parsed.
+type celsius float64
+
+func TestDecoderTakesPrecedenceOverKind(t *testing.T) {
+	type testType struct {
+		Max celsius `env:"MAX_TEMP"`
+	}
+	t.Setenv("MAX_TEMP", "21.5C")
+
+	parse := func(s string) (celsius, error) {
+		s, ok := strings.CutSuffix(s, "C")
+		if !ok {
+			return 0, errors.New("missing unit")
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return celsius(f), err
+	}
+
+	var config testType
+	require.NoError(t, Load(&config, WithDecoder(parse)))
+	assert.Equal(t, celsius(21.5), config.Max)
+}
+
+func TestDecoderNil(t *testing.T) {
+	assert.EqualError(t, RegisterDecoder[*url.URL](nil), "decoder for *url.URL must not be nil")
+
+	var config struct{}
+	err := Load(&config, WithDecoder[*regexp.Regexp](nil))
+	assert.EqualError(t, err, "decoder for *regexp.Regexp must not be nil")
+}
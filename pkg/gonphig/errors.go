@@ -0,0 +1,88 @@
+package gonphig
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/m-sossich/gonphig/internal/parser"
+	"github.com/m-sossich/gonphig/internal/validation"
+)
+
+// FieldError is a failure to load or validate a single configuration field
+// that has no more specific type, such as an unknown validate rule. It
+// carries the field's full path and, when it has one, its env var or flag
+// name.
+type FieldError = validation.FieldError
+
+// MissingFieldError reports a field tagged validate:"required" that no source
+// set:
+//
+//	var missing *gonphig.MissingFieldError
+//	if errors.As(err, &missing) {
+//		log.Fatalf("please set %s", missing.Key)
+//	}
+type MissingFieldError = validation.MissingFieldError
+
+// RuleError reports a field whose value breaks a validate rule, such as
+// min=1024 on a port of 80. Msg says how, e.g. "must be at least 1024, got
+// 80".
+type RuleError = validation.RuleError
+
+// UnsupportedFormatError reports a file passed to WithFile whose extension
+// has no parser; see RegisterParser.
+type UnsupportedFormatError = parser.UnsupportedFormatError
+
+// ParseError reports a raw value that could not be converted to its field's
+// type, such as PORT=eighty for an int field.
+type ParseError struct {
+	// Path is the dotted path of the field, e.g. "DB.Port".
+	Path string
+	// Source is the name of the source the value came from: "flags", "env",
+	// "default", a file path, or the Name of a custom Source.
+	Source string
+	// Key is what the source holds the value under: the env var or .env key
+	// (prefix included) or the flag name. It is empty for defaults.
+	Key string
+	// Raw is the value that failed to parse — for defaults, the tag as
+	// written.
+	Raw string
+	// Err is the underlying conversion error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// UnsupportedTypeError reports a field whose type gonphig cannot set, such as
+// a chan or func field.
+type UnsupportedTypeError struct {
+	// Path is the dotted path of the field.
+	Path string
+	// Type is the field's type.
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("invalid field[%s] type[%s]", e.Path, e.Type)
+}
+
+// InvalidTargetError reports a value passed to Load that is not a non-nil
+// pointer to a struct.
+type InvalidTargetError struct {
+	// Type is the type of the value passed, nil for a nil interface.
+	Type reflect.Type
+}
+
+func (e *InvalidTargetError) Error() string {
+	switch {
+	case e.Type == nil:
+		return "configuration must not be nil"
+	case e.Type.Kind() == reflect.Struct:
+		return "configuration to load needs to be a pointer"
+	default:
+		return "invalid configuration structure"
+	}
+}
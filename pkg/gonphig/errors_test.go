@@ -0,0 +1,222 @@
+package gonphig
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// joinedErrors returns the errors joined into err.
+func joinedErrors(t *testing.T, err error) []error {
+	t.Helper()
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok, "expected a joined error, got %T", err)
+	return joined.Unwrap()
+}
+
+func TestErrorsReportEveryMissingField(t *testing.T) {
+	type testType struct {
+		Host string `env:"DB_HOST" validate:"required"`
+		Port int    `env:"DB_PORT" validate:"required"`
+		DB   struct {
+			User string `env:"USER" validate:"required"`
+		} `env-prefix:"DB"`
+	}
+
+	var config testType
+	err := Load(&config, WithEnvPrefix("APP"))
+	require.Error(t, err)
+
+	assert.Equal(t, []error{
+		&MissingFieldError{Path: "Host", Key: "APP_DB_HOST"},
+		&MissingFieldError{Path: "Port", Key: "APP_DB_PORT"},
+		&MissingFieldError{Path: "DB.User", Key: "APP_DB_USER"},
+	}, joinedErrors(t, err))
+	assert.Contains(t, err.Error(), "missing required configuration: Host")
+	assert.Contains(t, err.Error(), "missing required configuration: Port")
+}
+
+func TestErrorsJoinParseAndValidationFailures(t *testing.T) {
+	type testType struct {
+		Port    int     `env:"PORT"`
+		Rate    float64 `env:"RATE"`
+		Timeout string  `env:"TIMEOUT" validate:"required"`
+	}
+
+	t.Setenv("PORT", "eighty")
+	t.Setenv("RATE", "fast")
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+
+	errs := joinedErrors(t, err)
+	require.Len(t, errs, 3)
+	assert.IsType(t, &ParseError{}, errs[0])
+	assert.IsType(t, &ParseError{}, errs[1])
+	assert.Equal(t, &MissingFieldError{Path: "Timeout", Key: "TIMEOUT"}, errs[2])
+}
+
+func TestErrorsFailedFieldIsNotAlsoMissing(t *testing.T) {
+	type testType struct {
+		Port int `env:"PORT" validate:"required"`
+	}
+
+	t.Setenv("PORT", "eighty")
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+	require.Len(t, joinedErrors(t, err), 1)
+	assert.NotContains(t, err.Error(), "missing required")
+}
+
+func TestErrorsParseError(t *testing.T) {
+	type testType struct {
+		DB struct {
+			Port int `env:"PORT"`
+		} `env-prefix:"DB"`
+	}
+
+	t.Setenv("DB_PORT", "eighty")
+
+	var config testType
+	err := Load(&config)
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, "DB.Port", pe.Path)
+	assert.Equal(t, "env", pe.Source)
+	assert.Equal(t, "DB_PORT", pe.Key)
+	assert.Equal(t, "eighty", pe.Raw)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+}
+
+func TestErrorsParseErrorFromDotEnv(t *testing.T) {
+	type testType struct {
+		Port int `env:"string-env"`
+	}
+
+	var config testType
+	err := Load(&config, WithFile(configDotEnvFile))
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, configDotEnvFile, pe.Source)
+	assert.Equal(t, "string-env", pe.Key)
+	assert.Equal(t, "Hello", pe.Raw)
+}
+
+func TestErrorsInvalidFlagFailsParse(t *testing.T) {
+	var config withFlagsConfig
+	fs := newFlagSet(t.Name())
+	fs.SetOutput(io.Discard)
+	err := Load(&config, WithFlags(fs, []string{"--int-flag=one", "--bool-flag=maybe"}))
+	require.Error(t, err)
+
+	var pe *ParseError
+	assert.False(t, errors.As(err, &pe), "the FlagSet reports invalid flags itself")
+	assert.EqualError(t, err, `invalid value "one" for flag -int-flag: parse error`)
+}
+
+func TestErrorsUnsupportedType(t *testing.T) {
+	type testType struct {
+		Events chan string `env:"EVENTS"`
+	}
+
+	var config testType
+	err := Load(&config)
+
+	var ute *UnsupportedTypeError
+	require.True(t, errors.As(err, &ute))
+	assert.Equal(t, "Events", ute.Path)
+	assert.Equal(t, reflect.TypeOf(config.Events), ute.Type)
+}
+
+func TestErrorsOtherFieldFailures(t *testing.T) {
+	type testType struct {
+		Hosts []string `flag:"hosts"`
+		Port  int      `env:"PORT" validate:"positive"`
+	}
+
+	var config testType
+	err := Load(&config, WithFlags(flag.NewFlagSet(t.Name(), flag.ContinueOnError), nil))
+
+	errs := joinedErrors(t, err)
+	require.Len(t, errs, 2)
+	var fe *FieldError
+	require.True(t, errors.As(errs[0], &fe))
+	assert.Equal(t, "Hosts", fe.Path)
+	assert.Equal(t, "hosts", fe.Key)
+	require.True(t, errors.As(errs[1], &fe))
+	assert.Equal(t, "Port", fe.Path)
+	assert.Equal(t, "PORT", fe.Key)
+}
+
+func TestErrorsUnsupportedFormat(t *testing.T) {
+	var config parentConfig
+	err := Load(&config, WithFile("config.ini"))
+
+	var ufe *UnsupportedFormatError
+	require.True(t, errors.As(err, &ufe))
+	assert.Equal(t, "config.ini", ufe.Path)
+	assert.Equal(t, ".ini", ufe.Ext)
+}
+
+func TestErrorsInvalidTarget(t *testing.T) {
+	var config parentConfig
+	for _, target := range []any{nil, config, new(int)} {
+		var ite *InvalidTargetError
+		require.True(t, errors.As(Load(target), &ite))
+		assert.Equal(t, reflect.TypeOf(target), ite.Type)
+	}
+}
+
+func TestErrorsNameFullFieldPath(t *testing.T) {
+	type endpoint struct {
+		URL  string `validate:"required"`
+		Port int    `env:"PORT"`
+	}
+	type testType struct {
+		DB struct {
+			Primary endpoint `env-prefix:"PRIMARY"`
+			Replica endpoint `env-prefix:"REPLICA"`
+		}
+		Events chan string `env:"EVENTS"`
+	}
+
+	t.Setenv("REPLICA_PORT", "eighty")
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		`DB.Replica.Port: strconv.ParseInt: parsing "eighty": invalid syntax`,
+		"invalid field[Events] type[chan string]",
+		"missing required configuration: DB.Primary.URL",
+		"missing required configuration: DB.Replica.URL",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestErrorsRuleFailuresNameFullFieldPath(t *testing.T) {
+	type testType struct {
+		Server struct {
+			Debug bool `validate:"required"`
+			Port  int  `validate:"positive"`
+		}
+	}
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not supported on bool field Server.Debug")
+	assert.Contains(t, err.Error(), `unknown validation rule "positive" on field Server.Port`)
+}
@@ -0,0 +1,39 @@
+package gonphig
+
+import "github.com/m-sossich/gonphig/internal/parser"
+
+// FileParser parses the raw bytes of a configuration file into target. For
+// KindStruct parsers target is the pointer passed to Load; for KindKV parsers
+// it is a *map[string]string pointing at a nil map, which the parser must
+// allocate.
+type FileParser = parser.FileParser
+
+// Kind controls how Load routes the result of a FileParser.
+type Kind = parser.Kind
+
+const (
+	// KindStruct parsers unmarshal directly into the configuration struct,
+	// like the built-in YAML, TOML and JSON parsers. Their values rank with
+	// the other configuration files, above defaults. Without the per-field
+	// presence the built-in formats report, a field counts as set only when
+	// decoding changes its value.
+	KindStruct = parser.KindStruct
+	// KindKV parsers produce flat key-value pairs, like the built-in dotenv
+	// parser. Keys are matched against env tags (including any env prefix)
+	// and rank with .env files, between env vars and configuration files.
+	KindKV = parser.KindKV
+)
+
+// RegisterParser makes WithFile accept files with extension ext (e.g. ".hcl"
+// or "hcl") and parse them with p. Registering an extension that already has
+// a parser — built-in or not — replaces it. Registration is global; call it
+// from an init function or before the first Load that needs it.
+//
+//	func init() {
+//		if err := gonphig.RegisterParser(".ini", parseINI, gonphig.KindKV); err != nil {
+//			panic(err)
+//		}
+//	}
+func RegisterParser(ext string, p FileParser, kind Kind) error {
+	return parser.Register(ext, p, kind)
+}
@@ -0,0 +1,82 @@
+package gonphig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/m-sossich/gonphig/internal/parser"
+)
+
+// propsParser reads "key: value" lines into a flat map.
+var propsParser FileParser = func(data []byte, target any) error {
+	m := target.(*map[string]string)
+	*m = make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), ":"); ok {
+			(*m)[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return scanner.Err()
+}
+
+// jsoncParser strips whole-line // comments before decoding JSON.
+var jsoncParser FileParser = func(data []byte, target any) error {
+	var kept [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("//")) {
+			kept = append(kept, line)
+		}
+	}
+	return json.Unmarshal(bytes.Join(kept, []byte("\n")), target)
+}
+
+func TestRegisterKVParser(t *testing.T) {
+	t.Cleanup(parser.Snapshot())
+	require.NoError(t, RegisterParser(".props", propsParser, KindKV))
+
+	var config parentConfig
+	err := Load(&config, WithFile("config-test.props"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello", config.Field)
+	assert.Equal(t, 1, config.Child.Int)
+	assert.Equal(t, true, config.Child.Child.Bool)
+}
+
+func TestRegisterKVParserOverriddenByEnv(t *testing.T) {
+	t.Cleanup(parser.Snapshot())
+	require.NoError(t, RegisterParser("props", propsParser, KindKV))
+	t.Setenv("string-env", "from-env")
+
+	var config parentConfig
+	err := Load(&config, WithFile("config-test.props"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", config.Field)
+}
+
+func TestRegisterStructParser(t *testing.T) {
+	t.Cleanup(parser.Snapshot())
+	require.NoError(t, RegisterParser(".jsonc", jsoncParser, KindStruct))
+
+	var config parentConfig
+	err := Load(&config, WithFile("config-test.jsonc"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello", config.Field)
+	assert.Equal(t, 1, config.Child.Int)
+	assert.Equal(t, true, config.Child.Child.Bool)
+}
+
+func TestRegisterParserInvalidInput(t *testing.T) {
+	assert.Error(t, RegisterParser("", propsParser, KindKV))
+	assert.Error(t, RegisterParser(".", propsParser, KindKV))
+	assert.Error(t, RegisterParser(".props", nil, KindKV))
+	assert.Error(t, RegisterParser(".props", propsParser, Kind(42)))
+}
@@ -0,0 +1,87 @@
+package gonphig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SourceID identifies a built-in configuration source in WithPrecedence.
+type SourceID string
+
+const (
+	// SourceFlags is CLI flags, enabled by WithArgs or WithFlags.
+	SourceFlags SourceID = "flags"
+	// SourceEnv is the process environment, read through env tags.
+	SourceEnv SourceID = "env"
+	// SourceDotEnv is .env files (and other KindKV files) passed to WithFile.
+	SourceDotEnv SourceID = "dotenv"
+	// SourceFile is YAML, TOML and JSON files (and other KindStruct files)
+	// passed to WithFile.
+	SourceFile SourceID = "file"
+	// SourceDefault is the default struct tag.
+	SourceDefault SourceID = "default"
+)
+
+// defaultPrecedence is the order Load uses without WithPrecedence.
+var defaultPrecedence = []SourceID{SourceFlags, SourceEnv, SourceDotEnv, SourceFile, SourceDefault}
+
+// WithPrecedence replaces the order in which sources win, from highest to
+// lowest. Every built-in source must be listed exactly once; Load returns an
+// error otherwise. The default order is:
+//
+//	gonphig.WithPrecedence(
+//		gonphig.SourceFlags, gonphig.SourceEnv, gonphig.SourceDotEnv,
+//		gonphig.SourceFile, gonphig.SourceDefault,
+//	)
+//
+// For each field, the highest-ranked source that sets it wins. Ranking
+// SourceDotEnv above SourceEnv lets a local .env file beat the process
+// environment; ranking SourceDefault above SourceFile makes defaults beat
+// file values, so files only fill fields without a default.
+func WithPrecedence(order ...SourceID) Option {
+	return func(s *settings) {
+		s.precedence = append([]SourceID{}, order...)
+	}
+}
+
+// validatePrecedence checks that order is a permutation of the built-in
+// sources.
+func validatePrecedence(order []SourceID) error {
+	seen := make(map[SourceID]bool, len(order))
+	for _, id := range order {
+		if !isBuiltinSource(id) {
+			return fmt.Errorf("invalid precedence: unknown source %q", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("invalid precedence: source %q listed more than once", id)
+		}
+		seen[id] = true
+	}
+	var missing []string
+	for _, id := range defaultPrecedence {
+		if !seen[id] {
+			missing = append(missing, string(id))
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("invalid precedence: missing " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func isBuiltinSource(id SourceID) bool {
+	for _, builtin := range defaultPrecedence {
+		if id == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// outranks reports whether the source at index a of l.sources wins over the
+// one at index b. Every source outranks -1, which stands for "no source set
+// the field".
+func (l *loader) outranks(a, b int) bool {
+	return b < 0 || a < b
+}
@@ -0,0 +1,132 @@
+package gonphig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecedenceDotEnvBeatsEnv(t *testing.T) {
+	t.Setenv("string-env", "from-real-env")
+
+	var config parentConfig
+	err := Load(&config,
+		WithFile(configDotEnvFile),
+		WithPrecedence(SourceFlags, SourceDotEnv, SourceEnv, SourceFile, SourceDefault),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", config.Field)
+}
+
+func TestPrecedenceEnvStillFillsFieldsMissingFromDotEnv(t *testing.T) {
+	type testType struct {
+		Host string `env:"string-env"`
+		Only string `env:"ONLY_IN_ENV"`
+	}
+
+	t.Setenv("ONLY_IN_ENV", "env-value")
+
+	var config testType
+	err := Load(&config,
+		WithFile(configDotEnvFile),
+		WithPrecedence(SourceFlags, SourceDotEnv, SourceEnv, SourceFile, SourceDefault),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", config.Host)
+	assert.Equal(t, "env-value", config.Only)
+}
+
+func TestPrecedenceFileBeatsEnv(t *testing.T) {
+	t.Setenv("string-env", "from-env")
+	t.Setenv("int-env", "100")
+
+	type testType struct {
+		Field string `env:"string-env"`
+		Other string `env:"int-env"`
+	}
+
+	var config testType
+	err := Load(&config,
+		WithFile(configTestFile),
+		WithPrecedence(SourceFlags, SourceFile, SourceEnv, SourceDotEnv, SourceDefault),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", config.Field) // set in the file
+	assert.Equal(t, "100", config.Other)   // not in the file — env fills it
+}
+
+func TestPrecedenceDefaultBeatsFile(t *testing.T) {
+	type testType struct {
+		Field string `default:"fallback"`
+		Child struct {
+			Int int
+		}
+	}
+
+	var config testType
+	err := Load(&config,
+		WithFile(configTestFile),
+		WithPrecedence(SourceFlags, SourceEnv, SourceDotEnv, SourceDefault, SourceFile),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", config.Field) // default wins
+	assert.Equal(t, 1, config.Child.Int)      // no default — file fills it
+}
+
+func TestPrecedenceEnvBeatsFlags(t *testing.T) {
+	type testType struct {
+		Host string `env:"PREC_HOST" flag:"host"`
+		Port int    `env:"PREC_PORT" flag:"port"`
+	}
+
+	t.Setenv("PREC_HOST", "from-env")
+
+	var config testType
+	err := Load(&config,
+		WithFlags(newFlagSet(t.Name()), []string{"--host=from-flag", "--port=9090"}),
+		WithPrecedence(SourceEnv, SourceFlags, SourceDotEnv, SourceFile, SourceDefault),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", config.Host) // env outranks the flag
+	assert.Equal(t, 9090, config.Port)       // no env var — the flag applies
+}
+
+func TestPrecedenceDefaultOrderUnchanged(t *testing.T) {
+	type testType struct {
+		IntB int `env:"intb-env" default:"1"`
+		IntC int `env:"intb-env" flag:"intc-flag" default:"1"`
+	}
+
+	t.Setenv("intb-env", "2")
+
+	var config testType
+	err := Load(&config,
+		WithFlags(newFlagSet(t.Name()), []string{"--intc-flag=3"}),
+		WithPrecedence(defaultPrecedence...),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, config.IntB)
+	assert.Equal(t, 3, config.IntC)
+}
+
+func TestPrecedenceValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []SourceID
+		msg   string
+	}{
+		{"unknown", []SourceID{SourceFlags, SourceEnv, SourceDotEnv, SourceFile, SourceDefault, "vault"}, `unknown source "vault"`},
+		{"duplicate", []SourceID{SourceFlags, SourceEnv, SourceEnv, SourceDotEnv, SourceFile, SourceDefault}, `source "env" listed more than once`},
+		{"missing", []SourceID{SourceFlags, SourceEnv, SourceFile, SourceDefault}, "missing dotenv"},
+		{"empty", []SourceID{}, "missing flags, env, dotenv, file, default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config parentConfig
+			err := Load(&config, WithPrecedence(tt.order...))
+			require.Error(t, err)
+			assert.Equal(t, "invalid precedence: "+tt.msg, err.Error())
+		})
+	}
+}
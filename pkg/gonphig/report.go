@@ -0,0 +1,165 @@
+package gonphig
+
+import (
+	"encoding"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Origin is the value one source holds for a field.
+type Origin struct {
+	// Source is the name of the source: "flags", "env", "default", a file
+	// path, or the Name of a custom Source.
+	Source string
+	// Key is what the source holds the value under: the env var or .env key
+	// (prefix included), the flag name, or the dotted key in a file (e.g.
+	// "db.port"). It is empty for defaults.
+	Key string
+	// Raw is the value as the source holds it. Values decoded from files
+	// are formatted with fmt.
+	Raw string
+}
+
+func (o Origin) String() string {
+	if o.Key == "" {
+		return fmt.Sprintf("%s=%q", o.Source, o.Raw)
+	}
+	return fmt.Sprintf("%s %s=%q", o.Source, o.Key, o.Raw)
+}
+
+// FieldReport describes how a field got its value: the winning source, and
+// the lower-ranked sources that set it too, highest first.
+type FieldReport struct {
+	Origin
+	Overridden []Origin
+}
+
+// Report maps dotted field paths, such as "DB.Port", to how each field got
+// its value. Fields that no source set are absent.
+type Report map[string]FieldReport
+
+// String lists the report one field per line, sorted by path:
+//
+//	DB.Port: env DB_PORT="5432" (overrides config.yml db.port="5433", default="5432")
+func (r Report) String() string {
+	var b strings.Builder
+	for _, path := range slices.Sorted(maps.Keys(r)) {
+		f := r[path]
+		fmt.Fprintf(&b, "%s: %s", path, f.Origin)
+		if len(f.Overridden) > 0 {
+			overridden := make([]string, len(f.Overridden))
+			for i, o := range f.Overridden {
+				overridden[i] = o.String()
+			}
+			fmt.Fprintf(&b, " (overrides %s)", strings.Join(overridden, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// WithProvenance makes Load record in report which source set each field,
+// with the key and raw value it used and any values it overrode. The report
+// is filled in even when Load fails, covering the fields resolved so far.
+//
+//	var report gonphig.Report
+//	err := gonphig.Load(&cfg, gonphig.WithFile("config.yml"), gonphig.WithProvenance(&report))
+//	log.Print(report)
+func WithProvenance(report *Report) Option {
+	return func(s *settings) {
+		s.report = report
+	}
+}
+
+// addOrigin records that a source sets the field at path, keeping the
+// field's origins in rank order.
+func (l *loader) addOrigin(path string, o origin) {
+	found := l.report[path]
+	i, _ := slices.BinarySearchFunc(found, o.src, func(f origin, src int) int { return f.src - src })
+	l.report[path] = slices.Insert(found, i, o)
+}
+
+// buildReport converts the recorded origins into a Report. Fields that are
+// never resolved — untagged fields and maps, which only Decode sources set —
+// are added from the sources that decoded them.
+func (l *loader) buildReport() Report {
+	resolved := make(map[string]bool, len(l.report))
+	for path := range l.report {
+		resolved[path] = true
+	}
+	for i, src := range l.sources {
+		for path, key := range src.fields {
+			if !resolved[path] {
+				l.addOrigin(path, origin{src: i, key: key, raw: src.raw[path]})
+			}
+		}
+	}
+	report := make(Report, len(l.report))
+	for path, found := range l.report {
+		origins := make([]Origin, len(found))
+		for i, o := range found {
+			origins[i] = Origin{Source: l.sources[o.src].src.Name(), Key: o.key, Raw: o.raw}
+		}
+		report[path] = FieldReport{Origin: origins[0], Overridden: origins[1:]}
+	}
+	return report
+}
+
+// formatFields formats the value of every field of the struct v listed in
+// set, keyed by path.
+func formatFields(v reflect.Value, set map[string]string) map[string]string {
+	out := make(map[string]string, len(set))
+	for path := range set {
+		if f, ok := fieldByPath(v, path); ok {
+			out[path] = formatValue(f)
+		}
+	}
+	return out
+}
+
+// formatValue formats the addressable value v as text: through MarshalText
+// when it implements encoding.TextMarshaler, so that it reads back through
+// UnmarshalText, then through String, and with fmt otherwise. A non-nil
+// pointer without a String method of its own is formatted as the value it
+// points to, and a []byte as the text it holds.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		if _, ok := v.Interface().(fmt.Stringer); !ok {
+			return formatValue(v.Elem())
+		}
+	}
+	switch p := v.Addr().Interface().(type) {
+	case *[]byte:
+		return string(*p)
+	case encoding.TextMarshaler:
+		if b, err := p.MarshalText(); err == nil {
+			return string(b)
+		}
+	case fmt.Stringer:
+		return p.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// fieldByPath returns the field of the struct v at the dotted path,
+// following non-nil pointers to structs.
+func fieldByPath(v reflect.Value, path string) (reflect.Value, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		if v = v.FieldByName(name); !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
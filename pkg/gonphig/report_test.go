@@ -0,0 +1,143 @@
+package gonphig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportRecordsWinnerAndOverridden(t *testing.T) {
+	type testType struct {
+		Field string `env:"string-env" yaml:"field" default:"fallback"`
+	}
+
+	t.Setenv("string-env", "from-env")
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config, WithFile(configTestFile), WithProvenance(&report)))
+
+	field := report["Field"]
+	assert.Equal(t, Origin{Source: "env", Key: "string-env", Raw: "from-env"}, field.Origin)
+	assert.Equal(t, []Origin{
+		{Source: configTestFile, Key: "field", Raw: "Hello"},
+		{Source: "default", Raw: "fallback"},
+	}, field.Overridden)
+}
+
+func TestReportNestedFileKeys(t *testing.T) {
+	var config parentConfig
+	var report Report
+	require.NoError(t, Load(&config, WithFile(configTestFile), WithProvenance(&report)))
+
+	assert.Equal(t, Origin{Source: configTestFile, Key: "child.int", Raw: "1"}, report["Child.Int"].Origin)
+	assert.Equal(t, Origin{Source: configTestFile, Key: "child.child.bool", Raw: "true"}, report["Child.Child.Bool"].Origin)
+}
+
+func TestReportUntaggedFileField(t *testing.T) {
+	type testType struct {
+		Field string
+	}
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config, WithFile(configTestFile), WithProvenance(&report)))
+	assert.Equal(t, Origin{Source: configTestFile, Key: "field", Raw: "Hello"}, report["Field"].Origin)
+}
+
+func TestReportEnvPrefixAndDotEnv(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+	}
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config,
+		WithEnvPrefix("BILLING"),
+		WithFile("config-prefix.env"),
+		WithProvenance(&report),
+	))
+	assert.Equal(t, "config-prefix.env", report["Host"].Source)
+	assert.Equal(t, "BILLING_HOST", report["Host"].Key)
+	assert.Equal(t, config.Host, report["Host"].Raw)
+}
+
+func TestReportFlags(t *testing.T) {
+	type testType struct {
+		Port int `flag:"port" env:"PORT" default:"80"`
+	}
+
+	t.Setenv("PORT", "8080")
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config,
+		WithFlags(newFlagSet(t.Name()), []string{"--port=9090"}),
+		WithProvenance(&report),
+	))
+	assert.Equal(t, Origin{Source: "flags", Key: "port", Raw: "9090"}, report["Port"].Origin)
+	assert.Equal(t, []Origin{
+		{Source: "env", Key: "PORT", Raw: "8080"},
+		{Source: "default", Raw: "80"},
+	}, report["Port"].Overridden)
+}
+
+func TestReportOutrankedFlag(t *testing.T) {
+	type testType struct {
+		Port int `flag:"port" env:"PORT"`
+	}
+
+	t.Setenv("PORT", "8080")
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config,
+		WithFlags(newFlagSet(t.Name()), []string{"--port=9090"}),
+		WithPrecedence(SourceEnv, SourceFlags, SourceDotEnv, SourceFile, SourceDefault),
+		WithProvenance(&report),
+	))
+	assert.Equal(t, 8080, config.Port)
+	assert.Equal(t, Origin{Source: "env", Key: "PORT", Raw: "8080"}, report["Port"].Origin)
+	assert.Equal(t, []Origin{{Source: "flags", Key: "port", Raw: "9090"}}, report["Port"].Overridden)
+}
+
+func TestReportOmitsUnsetFields(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+	}
+
+	var config testType
+	var report Report
+	require.NoError(t, Load(&config, WithProvenance(&report)))
+	assert.Empty(t, report)
+}
+
+func TestReportString(t *testing.T) {
+	report := Report{
+		"Port": {
+			Origin:     Origin{Source: "env", Key: "PORT", Raw: "8080"},
+			Overridden: []Origin{{Source: "default", Raw: "80"}},
+		},
+		"Host": {Origin: Origin{Source: "config.yml", Key: "host", Raw: "db"}},
+	}
+	assert.Equal(t,
+		"Host: config.yml host=\"db\"\n"+
+			"Port: env PORT=\"8080\" (overrides default=\"80\")\n",
+		report.String())
+}
+
+func TestReportFilledOnError(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+
+	t.Setenv("HOST", "db")
+	t.Setenv("PORT", "eighty")
+
+	var config testType
+	var report Report
+	require.Error(t, Load(&config, WithProvenance(&report)))
+	assert.Equal(t, "db", report["Host"].Raw)
+}
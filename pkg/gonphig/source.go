@@ -0,0 +1,328 @@
+package gonphig
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/m-sossich/gonphig/internal/parser"
+)
+
+// Source is a configuration provider, such as a config service, a database
+// table or a directory of files. Load calls its Load method once per Load
+// call and ranks what it returns against the other sources by priority: for
+// each field, the highest-priority source that sets it wins.
+//
+// Env vars, .env files, YAML, TOML and JSON files and CLI flags are all
+// implemented as Sources; WithSource adds custom ones.
+type Source interface {
+	// Name identifies the source, e.g. by file path, in errors.
+	Name() string
+	// Load reads the source.
+	Load() (SourceData, error)
+}
+
+// SourceData is what a Source provides: flat key-value pairs, like .env
+// files, or a function decoding into the configuration struct, like YAML
+// files. Values and Decode are mutually exclusive; a SourceData with neither
+// sets nothing.
+type SourceData struct {
+	// Values maps keys to raw values, which are parsed like env vars. Each
+	// field reads the key named by its Tag struct tag. Empty values count as
+	// unset.
+	Values map[string]string
+	// Tag is the struct tag naming each field's key in Values. It defaults
+	// to "env", in which case keys include any env prefix.
+	Tag string
+	// Decode unmarshals the source into target, the pointer passed to Load,
+	// on top of the values decoded by lower-priority sources, so it must
+	// only touch the fields it sets. It returns their dotted paths (e.g.
+	// "DB.Port"), each mapped to the key the source holds it under (e.g.
+	// "db.port"), which may be empty. A nil map makes Load count every field
+	// whose value changed as set.
+	Decode func(target any) (map[string]string, error)
+}
+
+// Priorities of the built-in sources under the default precedence; higher
+// priorities win. WithPrecedence reassigns them by position: the first
+// source listed gets PriorityFlags, the next PriorityEnv, and so on.
+const (
+	PriorityFlags   = 500
+	PriorityEnv     = 400
+	PriorityDotEnv  = 300
+	PriorityFile    = 200
+	PriorityDefault = 100
+)
+
+// WithSource adds src as a configuration source ranked by priority. When two
+// sources share a priority, the one registered later wins; built-in sources
+// count as registered first. WithSource may be passed more than once.
+//
+//	gonphig.Load(&cfg,
+//		gonphig.WithFile("config.yml"),
+//		gonphig.WithSource(configMap("/etc/app"), gonphig.PriorityFile+50),
+//	)
+func WithSource(src Source, priority int) Option {
+	return func(s *settings) {
+		s.sources = append(s.sources, customSource{src: src, priority: priority})
+	}
+}
+
+// customSource is a source added with WithSource.
+type customSource struct {
+	src      Source
+	priority int
+}
+
+// rankedSource is a source of a single Load call with its priority and, once
+// loaded, its data.
+type rankedSource struct {
+	src      Source
+	id       SourceID // built-in source, or "" for sources added with WithSource
+	priority int
+	data     SourceData
+	fields   map[string]string // fields set by Decode, mapped to their keys
+	raw      map[string]string // fields set by Decode, formatted for reports
+}
+
+// rankSources lists every source of a Load call from highest to lowest
+// priority.
+func rankSources(s *settings) ([]*rankedSource, error) {
+	priority := make(map[SourceID]int, len(s.precedence))
+	for i, id := range s.precedence {
+		priority[id] = (len(s.precedence) - i) * 100
+	}
+	var out []*rankedSource
+	add := func(src Source, id SourceID, p int) {
+		out = append(out, &rankedSource{src: src, id: id, priority: p})
+	}
+	if s.hasFlags {
+		add(flagSource{fs: s.fs, args: s.args}, SourceFlags, priority[SourceFlags])
+	}
+	add(envSource{}, SourceEnv, priority[SourceEnv])
+	dotenv := make(map[string]string)
+	for _, path := range s.files {
+		parse, kind, err := parser.Lookup(path, s.strict)
+		if err != nil {
+			return nil, err
+		}
+		id := SourceFile
+		if kind == parser.KindKV {
+			id = SourceDotEnv
+		}
+		f := fileSource{path: path, parse: parse, kind: kind}
+		if parser.Layered(path) {
+			f.earlier = dotenv
+		}
+		add(f, id, priority[id])
+	}
+	add(defaultSource{}, SourceDefault, priority[SourceDefault])
+	for _, c := range s.sources {
+		if c.src == nil {
+			return nil, errors.New("source must not be nil")
+		}
+		add(c.src, "", c.priority)
+	}
+	// Reversing first makes the stable sort rank later registrations first
+	// among equal priorities.
+	slices.Reverse(out)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].priority > out[j].priority })
+	return out, nil
+}
+
+// load calls Load on the source and checks the result.
+func (r *rankedSource) load() error {
+	data, err := r.src.Load()
+	if err != nil {
+		return err
+	}
+	if data.Values != nil && data.Decode != nil {
+		return fmt.Errorf("source %s: Values and Decode are mutually exclusive", r.src.Name())
+	}
+	if data.Tag == "" {
+		data.Tag = readEnvKey
+	}
+	r.data = data
+	return nil
+}
+
+// envSource reads the process environment.
+type envSource struct{}
+
+func (envSource) Name() string { return string(SourceEnv) }
+
+func (envSource) Load() (SourceData, error) {
+	env := os.Environ()
+	vars := make(map[string]string, len(env))
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			vars[k] = v
+		}
+	}
+	return SourceData{Values: vars}, nil
+}
+
+// fileSource reads a file passed to WithFile with the parser registered for
+// its extension: KindKV files provide values, KindStruct files decode.
+// earlier, shared by the .env files of a Load, collects the values of those
+// loaded so far, so that a .env layer can reference the keys of the ones
+// before it. It is nil for other files, whose parsers get a nil map.
+type fileSource struct {
+	path    string
+	parse   parser.FileParser
+	kind    parser.Kind
+	earlier map[string]string
+}
+
+func (f fileSource) Name() string { return f.path }
+
+func (f fileSource) Load() (SourceData, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return SourceData{}, err
+	}
+	if f.kind == parser.KindKV {
+		vars := maps.Clone(f.earlier)
+		if err := f.parse(data, &vars); err != nil {
+			return SourceData{}, fmt.Errorf("%s: %w", f.path, err)
+		}
+		if f.earlier != nil {
+			maps.Copy(f.earlier, vars)
+		}
+		return SourceData{Values: vars}, nil
+	}
+	fields := parser.Fields(f.path)
+	return SourceData{Decode: func(target any) (map[string]string, error) {
+		if err := f.parse(data, target); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		if fields == nil {
+			return nil, nil
+		}
+		set, err := fields(data, reflect.TypeOf(target).Elem())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		return set, nil
+	}}, nil
+}
+
+// flagSource reads CLI flags, keyed by flag tags. Flags are registered while
+// the fields are resolved from the other sources, so Load loads it last.
+type flagSource struct {
+	fs   *flag.FlagSet
+	args []string
+}
+
+func (f flagSource) Name() string { return string(SourceFlags) }
+
+func (f flagSource) Load() (SourceData, error) {
+	if err := f.fs.Parse(f.args); err != nil {
+		return SourceData{}, err
+	}
+	vars := make(map[string]string)
+	f.fs.Visit(func(fl *flag.Flag) {
+		vars[fl.Name] = fl.Value.String()
+	})
+	return SourceData{Values: vars, Tag: readFlagKey}, nil
+}
+
+// defaultSource stands for the default struct tag. It is never loaded; the
+// loader reads the tag directly when the source is reached.
+type defaultSource struct{}
+
+func (defaultSource) Name() string { return string(SourceDefault) }
+
+func (defaultSource) Load() (SourceData, error) { return SourceData{}, nil }
+
+// registerFlag registers the flag name on the FlagSet for a field holding v,
+// with v as its default. A field of a type the flag package has a flag for —
+// string, bool, int, int64, uint, uint64, float64 or time.Duration, or a
+// pointer to one — gets that flag, so --help names its type. Any other field
+// gets a flagValue checking its argument with parse. Either way Parse
+// rejects an invalid value, and flagSource reads the values back as strings.
+func (l *loader) registerFlag(v reflect.Value, name, usage string, parse valueParser) {
+	t, def := v.Type(), v
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		def = reflect.Zero(t)
+		if !v.IsNil() {
+			def = v.Elem()
+		}
+	}
+	if t == durationType && l.builtinDecoder(t) {
+		l.fs.Duration(name, time.Duration(def.Int()), usage)
+		return
+	}
+	if _, ok := l.decoderFor(t); ok || parsesItself(t) {
+		l.fs.Var(newFlagValue(v, parse), name, usage)
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		l.fs.String(name, def.String(), usage)
+	case reflect.Bool:
+		l.fs.Bool(name, def.Bool(), usage)
+	case reflect.Int:
+		l.fs.Int(name, int(def.Int()), usage)
+	case reflect.Int64:
+		l.fs.Int64(name, def.Int(), usage)
+	case reflect.Uint:
+		l.fs.Uint(name, uint(def.Uint()), usage)
+	case reflect.Uint64:
+		l.fs.Uint64(name, def.Uint(), usage)
+	case reflect.Float64:
+		l.fs.Float64(name, def.Float(), usage)
+	default:
+		l.fs.Var(newFlagValue(v, parse), name, usage)
+	}
+}
+
+// flagValue is the flag.Value registered for flag-tagged fields the flag
+// package has no flag for. It checks each argument by parsing it into a
+// scratch value of the field's type, then records it raw, so flags yield
+// strings like every other key-value source. Until the flag is passed it
+// holds the value the field resolved to from the other sources, which --help
+// shows as the default. A zero value — a nil pointer included — is held as
+// "", so --help omits it like the flag package does for its own zero
+// defaults. A pointer to a bool is a boolean flag like a bool.
+type flagValue struct {
+	raw    string
+	isBool bool
+	check  func(s string) error
+}
+
+func newFlagValue(v reflect.Value, parse valueParser) *flagValue {
+	f := &flagValue{check: func(s string) error { return parse(reflect.New(v.Type()).Elem(), s) }}
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	f.isBool = t.Kind() == reflect.Bool
+	if b, ok := reflect.New(t).Interface().(interface{ IsBoolFlag() bool }); ok {
+		f.isBool = b.IsBoolFlag()
+	}
+	if !v.IsZero() {
+		f.raw = formatValue(v)
+	}
+	return f
+}
+
+func (f *flagValue) String() string { return f.raw }
+
+func (f *flagValue) Set(s string) error {
+	if err := f.check(s); err != nil {
+		return err
+	}
+	f.raw = s
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
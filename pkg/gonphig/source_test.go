@@ -0,0 +1,229 @@
+package gonphig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapSource is a key-value source backed by a map.
+type mapSource struct {
+	values map[string]string
+	tag    string
+}
+
+func (m mapSource) Name() string { return "map" }
+
+func (m mapSource) Load() (SourceData, error) {
+	return SourceData{Values: m.values, Tag: m.tag}, nil
+}
+
+// funcSource is a source whose Load is a function.
+type funcSource func() (SourceData, error)
+
+func (f funcSource) Name() string { return "func" }
+
+func (f funcSource) Load() (SourceData, error) { return f() }
+
+// dirSource reads a Kubernetes ConfigMap mounted as a directory: each file
+// name is a key and its content the value.
+type dirSource string
+
+func (d dirSource) Name() string { return string(d) }
+
+func (d dirSource) Load() (SourceData, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return SourceData{}, err
+	}
+	vars := make(map[string]string, len(entries))
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(string(d), e.Name()))
+		if err != nil {
+			return SourceData{}, err
+		}
+		vars[e.Name()] = strings.TrimSpace(string(data))
+	}
+	return SourceData{Values: vars}, nil
+}
+
+func TestSourceValuesRankedByPriority(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+
+	t.Setenv("HOST", "from-env")
+	src := mapSource{values: map[string]string{"HOST": "from-source", "PORT": "8080"}}
+
+	var below testType
+	require.NoError(t, Load(&below, WithSource(src, PriorityEnv-50)))
+	assert.Equal(t, "from-env", below.Host)
+	assert.Equal(t, 8080, below.Port) // not in env — the source fills it
+
+	var above testType
+	require.NoError(t, Load(&above, WithSource(src, PriorityEnv+50)))
+	assert.Equal(t, "from-source", above.Host)
+}
+
+func TestSourceTieGoesToLaterRegistration(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+	}
+
+	t.Setenv("HOST", "from-env")
+
+	var config testType
+	err := Load(&config,
+		WithSource(mapSource{values: map[string]string{"HOST": "first"}}, PriorityEnv),
+		WithSource(mapSource{values: map[string]string{"HOST": "second"}}, PriorityEnv),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "second", config.Host)
+}
+
+func TestSourceValuesUseEnvPrefix(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+	}
+
+	var config testType
+	err := Load(&config,
+		WithEnvPrefix("APP"),
+		WithSource(mapSource{values: map[string]string{"APP_HOST": "prefixed", "HOST": "bare"}}, PriorityDotEnv),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "prefixed", config.Host)
+}
+
+func TestSourceValuesWithCustomTag(t *testing.T) {
+	type testType struct {
+		Password string `vault:"db/password" env:"DB_PASSWORD"`
+	}
+
+	t.Setenv("DB_PASSWORD", "from-env")
+	src := mapSource{values: map[string]string{"db/password": "s3cret"}, tag: "vault"}
+
+	var config testType
+	require.NoError(t, Load(&config, WithSource(src, PriorityFlags+100)))
+	assert.Equal(t, "s3cret", config.Password)
+}
+
+func TestSourceDecode(t *testing.T) {
+	type testType struct {
+		Field string `env:"string-env"`
+		Port  int    `default:"80"`
+	}
+
+	src := funcSource(func() (SourceData, error) {
+		return SourceData{Decode: func(target any) (map[string]string, error) {
+			target.(*testType).Port = 0
+			return map[string]string{"Port": "port"}, nil
+		}}, nil
+	})
+
+	var config testType
+	require.NoError(t, Load(&config, WithFile(configTestFile), WithSource(src, PriorityFile)))
+	assert.Equal(t, "Hello", config.Field) // from the file, untouched by the source
+	assert.Equal(t, 0, config.Port)        // explicit zero beats the default
+}
+
+func TestSourceDecodeWithoutPresenceUsesChangedFields(t *testing.T) {
+	type testType struct {
+		Field string `env:"string-env" yaml:"field"`
+	}
+
+	t.Setenv("string-env", "from-env")
+	src := funcSource(func() (SourceData, error) {
+		return SourceData{Decode: func(target any) (map[string]string, error) {
+			target.(*testType).Field = "decoded"
+			return nil, nil
+		}}, nil
+	})
+
+	var config testType
+	require.NoError(t, Load(&config, WithSource(src, PriorityFlags+100)))
+	assert.Equal(t, "decoded", config.Field)
+}
+
+func TestSourceOutranksFlags(t *testing.T) {
+	type testType struct {
+		Port int `flag:"port"`
+	}
+
+	src := mapSource{values: map[string]string{"port": "9000"}, tag: "flag"}
+
+	var config testType
+	err := Load(&config,
+		WithFlags(newFlagSet(t.Name()), []string{"--port=8000"}),
+		WithSource(src, PriorityFlags+100),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 9000, config.Port)
+}
+
+func TestSourceConfigMapDirectory(t *testing.T) {
+	type testType struct {
+		LogLevel string `env:"LOG_LEVEL" default:"info"`
+		Workers  int    `env:"WORKERS"`
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "LOG_LEVEL"), []byte("debug\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "WORKERS"), []byte("4\n"), 0o600))
+
+	var config testType
+	require.NoError(t, Load(&config, WithSource(dirSource(dir), PriorityDotEnv)))
+	assert.Equal(t, "debug", config.LogLevel)
+	assert.Equal(t, 4, config.Workers)
+}
+
+func TestSourceLoadError(t *testing.T) {
+	type testType struct {
+		Host string `env:"HOST"`
+	}
+
+	boom := errors.New("config service unavailable")
+	src := funcSource(func() (SourceData, error) { return SourceData{}, boom })
+
+	var config testType
+	err := Load(&config, WithSource(src, PriorityEnv))
+	assert.ErrorIs(t, err, boom)
+}
+
+func TestSourceInvalidValue(t *testing.T) {
+	type testType struct {
+		Port int `env:"PORT"`
+	}
+
+	var config testType
+	err := Load(&config, WithSource(mapSource{values: map[string]string{"PORT": "eighty"}}, PriorityEnv))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Port")
+}
+
+func TestSourceNilIsRejected(t *testing.T) {
+	var config parentConfig
+	err := Load(&config, WithSource(nil, PriorityEnv))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "source must not be nil")
+}
+
+func TestSourceValuesAndDecodeAreExclusive(t *testing.T) {
+	src := funcSource(func() (SourceData, error) {
+		return SourceData{
+			Values: map[string]string{},
+			Decode: func(any) (map[string]string, error) { return nil, nil },
+		}, nil
+	})
+
+	var config parentConfig
+	err := Load(&config, WithSource(src, PriorityEnv))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mutually exclusive")
+}
@@ -0,0 +1,392 @@
+package gonphig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationRulesPass(t *testing.T) {
+	type testType struct {
+		Port     int           `env:"PORT"      validate:"min=1024,max=65535"`
+		Ratio    float64       `env:"RATIO"     validate:"min=0,max=1"`
+		Timeout  time.Duration `env:"TIMEOUT"   validate:"min=1s,max=1m"`
+		Name     string        `env:"NAME"      validate:"min=3,max=8"`
+		Code     string        `env:"CODE"      validate:"len=2"`
+		Hosts    []string      `env:"HOSTS"     validate:"min=1,max=3"`
+		LogLevel string        `env:"LOG_LEVEL" validate:"oneof=debug info warn"`
+		Region   string        `env:"REGION"    validate:"required,pattern=^[a-z]{2}-[a-z]+-[0-9]$"`
+	}
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("RATIO", "0.5")
+	t.Setenv("TIMEOUT", "30s")
+	t.Setenv("NAME", "billing")
+	t.Setenv("CODE", "ñu")
+	t.Setenv("HOSTS", "a,b")
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("REGION", "eu-west-1")
+
+	var config testType
+	require.NoError(t, Load(&config))
+}
+
+func TestValidationRuleFailures(t *testing.T) {
+	type testType struct {
+		Port     int           `env:"PORT"      validate:"min=1024"`
+		Ratio    float64       `env:"RATIO"     validate:"max=1"`
+		Timeout  time.Duration `env:"TIMEOUT"   validate:"max=1m"`
+		Name     string        `env:"NAME"      validate:"max=3"`
+		Code     string        `env:"CODE"      validate:"len=2"`
+		Hosts    []string      `env:"HOSTS"     validate:"min=1"`
+		LogLevel string        `env:"LOG_LEVEL" validate:"oneof=debug info warn"`
+		Retries  int           `env:"RETRIES"   validate:"oneof=1 3 5"`
+		Region   string        `env:"REGION"    validate:"pattern=^[a-z]{2}-[a-z]+-[0-9]$"`
+	}
+
+	t.Setenv("PORT", "80")
+	t.Setenv("RATIO", "1.5")
+	t.Setenv("TIMEOUT", "5m")
+	t.Setenv("NAME", "billing")
+	t.Setenv("CODE", "esp")
+	t.Setenv("LOG_LEVEL", "trace")
+	t.Setenv("RETRIES", "2")
+	t.Setenv("REGION", "EU")
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		"Port: must be at least 1024, got 80",
+		"Ratio: must be at most 1, got 1.5",
+		"Timeout: must be at most 1m, got 5m0s",
+		"Name: must have at most 3 characters, got 7",
+		"Code: must have exactly 2 characters, got 3",
+		"Hosts: must have at least 1 element, got 0",
+		`LogLevel: must be one of debug, info, warn, got "trace"`,
+		"Retries: must be one of 1, 3, 5, got 2",
+		`Region: must match pattern "^[a-z]{2}-[a-z]+-[0-9]$", got "EU"`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestValidationRuleError(t *testing.T) {
+	type testType struct {
+		Server struct {
+			Port int `env:"PORT" validate:"min=1024"`
+		} `env-prefix:"SERVER"`
+	}
+
+	t.Setenv("APP_SERVER_PORT", "80")
+
+	var config testType
+	err := Load(&config, WithEnvPrefix("APP"))
+
+	var re *RuleError
+	require.True(t, errors.As(err, &re))
+	assert.Equal(t, &RuleError{
+		Path:  "Server.Port",
+		Key:   "APP_SERVER_PORT",
+		Rule:  "min",
+		Param: "1024",
+		Msg:   "must be at least 1024, got 80",
+	}, re)
+}
+
+func TestValidationMissingRequiredSkipsOtherRules(t *testing.T) {
+	type testType struct {
+		Port int `env:"PORT" validate:"required,min=1024"`
+	}
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, []error{&MissingFieldError{Path: "Port", Key: "PORT"}}, joinedErrors(t, err))
+}
+
+func TestValidationMisusedRules(t *testing.T) {
+	type testType struct {
+		Debug   bool          `validate:"min=1"`
+		Port    int           `validate:"min=ten"`
+		Timeout time.Duration `validate:"max=10"`
+		Count   int           `validate:"len=3"`
+		Level   string        `validate:"oneof="`
+		Ratio   float64       `validate:"oneof=1 2"`
+		Name    string        `validate:"pattern=[a-"`
+	}
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+
+	errs := joinedErrors(t, err)
+	require.Len(t, errs, 7)
+	for _, e := range errs {
+		assert.IsType(t, &FieldError{}, e)
+	}
+	assert.Equal(t, []string{
+		`validate:"min=1" on field Debug: not supported on bool fields`,
+		`validate:"min=ten" on field Port: invalid integer "ten"`,
+		`validate:"max=10" on field Timeout: invalid duration "10"`,
+		`validate:"len=3" on field Count: not supported on int fields`,
+		`validate:"oneof=" on field Level: needs at least one value`,
+		`validate:"oneof=1 2" on field Ratio: not supported on float64 fields`,
+		"validate:\"pattern=[a-\" on field Name: invalid pattern: error parsing regexp: missing closing ]: `[a-`",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestValidationFormatsPass(t *testing.T) {
+	type testType struct {
+		Endpoint string `env:"ENDPOINT" validate:"url"`
+		Host     string `env:"HOST"     validate:"hostname"`
+		IP       string `env:"IP"       validate:"ip"`
+		Network  string `env:"NETWORK"  validate:"cidr"`
+		Listen   string `env:"LISTEN"   validate:"hostport"`
+		DB       string `env:"DB"       validate:"hostport"`
+		Port     int    `env:"PORT"     validate:"port"`
+		PortStr  string `env:"PORT_STR" validate:"port"`
+		Email    string `env:"EMAIL"    validate:"email"`
+		File     string `env:"FILE"     validate:"file"`
+		Dir      string `env:"DIR"      validate:"dir"`
+		AbsDir   string `env:"ABS_DIR"  validate:"absdir"`
+		Optional string `env:"OPTIONAL" validate:"url"`
+	}
+
+	t.Setenv("ENDPOINT", "https://api.example.com/v1")
+	t.Setenv("HOST", "db-1.internal.example.com")
+	t.Setenv("IP", "2001:db8::1")
+	t.Setenv("NETWORK", "10.0.0.0/8")
+	t.Setenv("LISTEN", ":8080")
+	t.Setenv("DB", "[::1]:5432")
+	t.Setenv("PORT", "443")
+	t.Setenv("PORT_STR", "65535")
+	t.Setenv("EMAIL", "ops@example.com")
+	t.Setenv("FILE", configTestFile)
+	t.Setenv("DIR", ".")
+	t.Setenv("ABS_DIR", t.TempDir())
+
+	var config testType
+	require.NoError(t, Load(&config))
+}
+
+func TestValidationFormatFailures(t *testing.T) {
+	type testType struct {
+		Endpoint string `env:"ENDPOINT" validate:"url"`
+		Host     string `env:"HOST"     validate:"hostname"`
+		IP       string `env:"IP"       validate:"ip"`
+		Network  string `env:"NETWORK"  validate:"cidr"`
+		Listen   string `env:"LISTEN"   validate:"hostport"`
+		Port     int    `env:"PORT"     validate:"port"`
+		Email    string `env:"EMAIL"    validate:"email"`
+		File     string `env:"FILE"     validate:"file"`
+		NotFile  string `env:"NOT_FILE" validate:"file"`
+		Dir      string `env:"DIR"      validate:"dir"`
+		AbsDir   string `env:"ABS_DIR"  validate:"absdir"`
+	}
+
+	t.Setenv("ENDPOINT", "api.example.com")
+	t.Setenv("HOST", "-db.example.com")
+	t.Setenv("IP", "10.0.0.256")
+	t.Setenv("NETWORK", "10.0.0.0")
+	t.Setenv("LISTEN", "db:http")
+	t.Setenv("PORT", "70000")
+	t.Setenv("EMAIL", "Ops <ops@example.com>")
+	t.Setenv("FILE", "missing.yml")
+	t.Setenv("NOT_FILE", ".")
+	t.Setenv("DIR", configTestFile)
+	t.Setenv("ABS_DIR", ".")
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		`Endpoint: must be a URL with a scheme and host, got "api.example.com"`,
+		`Host: must be a hostname, got "-db.example.com"`,
+		`IP: must be an IP address, got "10.0.0.256"`,
+		`Network: must be a CIDR prefix such as 10.0.0.0/8, got "10.0.0.0"`,
+		`Listen: must be host:port, got "db:http"`,
+		"Port: must be a port between 1 and 65535, got 70000",
+		`Email: must be an email address, got "Ops <ops@example.com>"`,
+		"File: must be an existing file: stat missing.yml: no such file or directory",
+		`NotFile: must be a regular file, got "."`,
+		`Dir: must be a directory, got "` + configTestFile + `"`,
+		`AbsDir: must be an absolute path, got "."`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestValidationMisusedFormats(t *testing.T) {
+	type testType struct {
+		Endpoint int    `validate:"url"`
+		Host     string `validate:"hostname=strict"`
+	}
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`validate:"url" on field Endpoint: not supported on int fields`,
+		`validate:"hostname=strict" on field Host: takes no parameter`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestValidationCrossFieldRules(t *testing.T) {
+	type testType struct {
+		TLS struct {
+			Enabled  bool   `env:"TLS_ENABLED"`
+			CertFile string `env:"TLS_CERT" validate:"required_if=Enabled true,file"`
+			KeyFile  string `env:"TLS_KEY"  validate:"required_with=CertFile"`
+		}
+		Cache struct {
+			Backend string `env:"CACHE_BACKEND" validate:"oneof=memory redis"`
+		}
+		Redis struct {
+			URL string `env:"REDIS_URL" validate:"required_if=Cache.Backend redis,url"`
+		}
+		Token    string        `env:"TOKEN"`
+		Password string        `env:"PASSWORD" validate:"required_without=Token,excluded_with=Token"`
+		MinConns int           `env:"MIN_CONNS"`
+		MaxConns int           `env:"MAX_CONNS" validate:"gtfield=MinConns"`
+		Idle     time.Duration `env:"IDLE"`
+		Lifetime time.Duration `env:"LIFETIME"  validate:"gtefield=Idle"`
+	}
+
+	t.Run("pass", func(t *testing.T) {
+		t.Setenv("TLS_ENABLED", "true")
+		t.Setenv("TLS_CERT", configTestFile)
+		t.Setenv("TLS_KEY", configTestFile)
+		t.Setenv("CACHE_BACKEND", "memory")
+		t.Setenv("TOKEN", "secret")
+		t.Setenv("MIN_CONNS", "2")
+		t.Setenv("MAX_CONNS", "10")
+		t.Setenv("IDLE", "1m")
+		t.Setenv("LIFETIME", "1m")
+
+		var config testType
+		require.NoError(t, Load(&config))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		t.Setenv("TLS_ENABLED", "true")
+		t.Setenv("CACHE_BACKEND", "redis")
+		t.Setenv("MIN_CONNS", "10")
+		t.Setenv("MAX_CONNS", "5")
+		t.Setenv("IDLE", "5m")
+		t.Setenv("LIFETIME", "1m")
+
+		var config testType
+		err := Load(&config)
+		require.Error(t, err)
+		assert.Equal(t, []string{
+			"TLS.CertFile: must be set when Enabled is true",
+			"Redis.URL: must be set when Cache.Backend is redis",
+			"Password: must be set when Token is not set",
+			"MaxConns: must be greater than MinConns (10), got 5",
+			"Lifetime: must be at least Idle (5m0s), got 1m0s",
+		}, strings.Split(err.Error(), "\n"))
+
+		var re *RuleError
+		require.True(t, errors.As(err, &re))
+		assert.Equal(t, "required_if", re.Rule)
+		assert.Equal(t, "Enabled true", re.Param)
+		assert.Equal(t, "TLS_CERT", re.Key)
+	})
+
+	t.Run("excluded", func(t *testing.T) {
+		t.Setenv("CACHE_BACKEND", "memory")
+		t.Setenv("TOKEN", "secret")
+		t.Setenv("PASSWORD", "hunter2")
+		t.Setenv("MAX_CONNS", "1")
+
+		var config testType
+		err := Load(&config)
+		require.Error(t, err)
+		assert.Equal(t, "Password: must not be set when Token is set", err.Error())
+	})
+}
+
+func TestValidationMisusedCrossFieldRules(t *testing.T) {
+	type testType struct {
+		Enabled bool    `validate:"required_with=Name"`
+		Name    string  `validate:"required_if=Enabled"`
+		Port    int     `validate:"gtfield=Ratio"`
+		Ratio   float64 `validate:"excluded_with=Missing"`
+		Host    string  `validate:"ltfield=Name"`
+	}
+
+	var config testType
+	config.Name = "x"
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`validate:"required_with=Name" on field Enabled: not supported on bool fields`,
+		`validate:"required_if=Enabled" on field Name: needs field and value pairs`,
+		`validate:"gtfield=Ratio" on field Port: field Ratio is float64, not int`,
+		`validate:"excluded_with=Missing" on field Ratio: no field Missing`,
+		`validate:"ltfield=Name" on field Host: not supported on string fields`,
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestValidationDive(t *testing.T) {
+	type testType struct {
+		Brokers []string          `env:"BROKERS" validate:"min=1,dive,hostport"`
+		Ports   []string          `yaml:"ports"  validate:"dive,required,port"`
+		Labels  map[string]string `yaml:"labels" validate:"dive,keys,hostname,endkeys,max=5"`
+		Hosts   map[string]string `yaml:"hosts"  validate:"dive,url"`
+	}
+
+	t.Setenv("BROKERS", "kafka-1:9092, kafka-2:9092, kafka-3")
+	config := testType{
+		Ports:  []string{"80", "", "http"},
+		Labels: map[string]string{"env": "production", "bad key": "x", "team": "ops"},
+		Hosts:  map[string]string{"api": "https://api.example.com"},
+	}
+	err := Load(&config)
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		`Brokers[2]: must be host:port, got "kafka-3"`,
+		"missing required configuration: Ports[1]",
+		`Ports[2]: must be a port between 1 and 65535, got "http"`,
+		`Labels[bad key]: must be a hostname, got "bad key"`,
+		"Labels[env]: must have at most 5 characters, got 10",
+	}, strings.Split(err.Error(), "\n"))
+
+	var re *RuleError
+	require.True(t, errors.As(err, &re))
+	assert.Equal(t, "Brokers[2]", re.Path)
+	assert.Equal(t, "BROKERS", re.Key)
+}
+
+func TestValidationDiveOnEmptySlice(t *testing.T) {
+	type testType struct {
+		Brokers []string `env:"BROKERS" validate:"dive,hostport"`
+		Peers   []string `env:"PEERS"   validate:"required,dive,hostport"`
+	}
+
+	var config testType
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, "missing required configuration: Peers", err.Error())
+}
+
+func TestValidationMisusedDive(t *testing.T) {
+	type testType struct {
+		Name   string            `validate:"dive,hostname"`
+		Hosts  []string          `validate:"dive,keys,hostname,endkeys"`
+		Labels map[string]string `validate:"dive,keys,hostname"`
+	}
+
+	config := testType{Hosts: []string{"a"}, Labels: map[string]string{"a": "b"}}
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		`validate:"dive" on field Name: not supported on string fields`,
+		`validate:"dive" on field Hosts: keys is only supported on maps`,
+		`validate:"dive" on field Labels: keys without endkeys`,
+	}, strings.Split(err.Error(), "\n"))
+}
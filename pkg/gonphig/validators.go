@@ -0,0 +1,45 @@
+package gonphig
+
+import "github.com/m-sossich/gonphig/internal/validation"
+
+// ValidatorFunc checks a field against the parameter of its rule — the text
+// after "=" in the validate tag, or "" — and returns an error saying how the
+// value breaks the rule, or nil. Load reports the error as a *RuleError whose
+// Msg is the error's text, so phrase it like the built-in rules: "must be at
+// least 1024, got 80".
+type ValidatorFunc = validation.Rule
+
+// ValidationField is the field a ValidatorFunc checks: its dotted Path, its
+// Value, the Parent struct holding it and the Root configuration. Its Lookup
+// method finds another field by a sibling name or a dotted path from the
+// root, as the cross-field rules do.
+type ValidationField = validation.Field
+
+// RegisterValidator makes fn available in validate tags as name, so that
+// validate:"bucket" or validate:"bucket=Region" calls it. Registering a name
+// that already has a validator — built-in or not — replaces it; required is
+// reserved. Registration is global; call it from an init function or before
+// the first Load that needs it.
+//
+//	func init() {
+//		err := gonphig.RegisterValidator("bucket", func(f gonphig.ValidationField, param string) error {
+//			region, _ := f.Lookup(param)
+//			if !strings.HasSuffix(f.Value.String(), "-"+region.String()) {
+//				return fmt.Errorf("must end in -%s, got %q", region.String(), f.Value.String())
+//			}
+//			return nil
+//		})
+//		if err != nil {
+//			panic(err)
+//		}
+//	}
+//
+// Besides tags, Load calls the Validate() error method of the configuration
+// struct and of every nested struct that has one, once loading and tag
+// validation succeeded. Nested structs are called first; a struct is skipped
+// when one of its nested structs failed. Errors from Validate methods are
+// returned as *FieldError, prefixed with the struct's path unless it is the
+// root.
+func RegisterValidator(name string, fn ValidatorFunc) error {
+	return validation.Register(name, fn)
+}
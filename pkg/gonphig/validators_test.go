@@ -0,0 +1,168 @@
+package gonphig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/m-sossich/gonphig/internal/validation"
+)
+
+func TestRegisterValidator(t *testing.T) {
+	t.Cleanup(validation.Snapshot())
+	require.NoError(t, RegisterValidator("bucket", func(f ValidationField, param string) error {
+		region, ok := f.Lookup(param)
+		if !ok {
+			return fmt.Errorf("no field %s", param)
+		}
+		if !strings.HasSuffix(f.Value.String(), "-"+region.String()) {
+			return fmt.Errorf("must end in -%s, got %q", region.String(), f.Value.String())
+		}
+		return nil
+	}))
+
+	type testType struct {
+		Region string `env:"REGION"`
+		Bucket string `env:"BUCKET" validate:"bucket=Region"`
+	}
+
+	t.Setenv("REGION", "eu")
+	t.Setenv("BUCKET", "logs-us")
+
+	var config testType
+	err := Load(&config)
+
+	var re *RuleError
+	require.True(t, errors.As(err, &re))
+	assert.Equal(t, &RuleError{
+		Path:  "Bucket",
+		Key:   "BUCKET",
+		Rule:  "bucket",
+		Param: "Region",
+		Msg:   `must end in -eu, got "logs-us"`,
+	}, re)
+
+	t.Setenv("BUCKET", "logs-eu")
+	require.NoError(t, Load(&config))
+}
+
+func TestRegisterValidatorReplaces(t *testing.T) {
+	t.Cleanup(validation.Snapshot())
+	type testType struct {
+		Name string `env:"NAME" validate:"lowercase"`
+	}
+	t.Setenv("NAME", "Billing")
+
+	require.NoError(t, RegisterValidator("lowercase", func(f ValidationField, _ string) error {
+		if f.Value.String() != strings.ToLower(f.Value.String()) {
+			return fmt.Errorf("must be lowercase, got %q", f.Value.String())
+		}
+		return nil
+	}))
+	var config testType
+	assert.EqualError(t, Load(&config), `Name: must be lowercase, got "Billing"`)
+
+	require.NoError(t, RegisterValidator("lowercase", func(ValidationField, string) error { return nil }))
+	require.NoError(t, Load(&config))
+}
+
+func TestRegisterValidatorErrors(t *testing.T) {
+	noop := func(ValidationField, string) error { return nil }
+	assert.EqualError(t, RegisterValidator("", noop), `invalid validator name ""`)
+	assert.EqualError(t, RegisterValidator("min=1", noop), `invalid validator name "min=1"`)
+	assert.EqualError(t, RegisterValidator("required", noop), `validator name "required" is reserved`)
+	assert.EqualError(t, RegisterValidator("bucket", nil), `validator "bucket" must not be nil`)
+}
+
+// validateCalls records the Validate methods called, in order.
+var validateCalls []string
+
+type poolConfig struct {
+	Min int `env:"POOL_MIN"`
+	Max int `env:"POOL_MAX"`
+}
+
+func (p poolConfig) Validate() error {
+	validateCalls = append(validateCalls, "DB.Pool")
+	if p.Min > p.Max {
+		return fmt.Errorf("min %d exceeds max %d", p.Min, p.Max)
+	}
+	return nil
+}
+
+type dbConfig struct {
+	Pool    poolConfig
+	Primary string `env:"PRIMARY"`
+}
+
+func (d *dbConfig) Validate() error {
+	validateCalls = append(validateCalls, "DB")
+	return nil
+}
+
+type serviceConfig struct {
+	Name string `env:"NAME" validate:"required"`
+	DB   dbConfig
+}
+
+func (s *serviceConfig) Validate() error {
+	validateCalls = append(validateCalls, "root")
+	if s.DB.Primary == "" {
+		return errors.New("a primary database is required")
+	}
+	return nil
+}
+
+func TestValidateMethods(t *testing.T) {
+	t.Setenv("NAME", "billing")
+	t.Setenv("PRIMARY", "db:5432")
+	t.Setenv("POOL_MAX", "10")
+
+	validateCalls = nil
+	var config serviceConfig
+	require.NoError(t, Load(&config))
+	assert.Equal(t, []string{"DB.Pool", "DB", "root"}, validateCalls)
+}
+
+func TestValidateMethodsRootError(t *testing.T) {
+	t.Setenv("NAME", "billing")
+
+	var config serviceConfig
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, "a primary database is required", err.Error())
+
+	var fe *FieldError
+	require.True(t, errors.As(err, &fe))
+	assert.Equal(t, "", fe.Path)
+}
+
+func TestValidateMethodsNestedFirst(t *testing.T) {
+	t.Setenv("NAME", "billing")
+	t.Setenv("POOL_MIN", "20")
+	t.Setenv("POOL_MAX", "10")
+
+	validateCalls = nil
+	var config serviceConfig
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, "DB.Pool: min 20 exceeds max 10", err.Error())
+	assert.Equal(t, []string{"DB.Pool"}, validateCalls, "structs holding a failed struct are not called")
+
+	var fe *FieldError
+	require.True(t, errors.As(err, &fe))
+	assert.Equal(t, "DB.Pool", fe.Path)
+}
+
+func TestValidateMethodsRunAfterTags(t *testing.T) {
+	validateCalls = nil
+	var config serviceConfig
+	err := Load(&config)
+	require.Error(t, err)
+	assert.Equal(t, "missing required configuration: Name", err.Error())
+	assert.Empty(t, validateCalls)
+}